@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/SAP-2025/auth-service/internal/config"
+	"github.com/SAP-2025/auth-service/internal/db"
+	"github.com/SAP-2025/auth-service/internal/models"
+	"github.com/SAP-2025/auth-service/internal/services"
+	"github.com/SAP-2025/auth-service/pkg"
+	"log"
+	"os"
+	"time"
+)
+
+// runCommand runs an administrative subcommand instead of the server
+func runCommand(cfg *config.Config, name string, args []string) error {
+	switch name {
+	case "migrate":
+		return migrate(cfg, args)
+	case "import-policies":
+		return importPolicies(cfg, args)
+	case "revoke-tokens":
+		return revokeTokens(cfg, args)
+	default:
+		return fmt.Errorf("unknown command, available: migrate, import-policies, revoke-tokens")
+	}
+}
+
+// migrate applies, reverts or lists the schema migrations:
+// migrate up|down|status [-steps N]
+func migrate(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: migrate up|down|status [-steps N]")
+	}
+	action := args[0]
+
+	flags := flag.NewFlagSet("migrate "+action, flag.ContinueOnError)
+	steps := flags.Int("steps", 0, "number of migrations to apply or revert, up applies all and down reverts one by default")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	database, err := db.Connect(cfg)
+	if err != nil {
+		return err
+	}
+	migrator, err := db.NewMigrator(database)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch action {
+	case "up":
+		return migrator.Up(ctx, *steps)
+	case "down":
+		return migrator.Down(ctx, *steps)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = "applied " + status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, applied)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate action %q, available: up, down, status", action)
+	}
+}
+
+// importPolicies stores a policy file in Postgres as a new version, which
+// running instances pick up on their next reload
+func importPolicies(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("import-policies", flag.ContinueOnError)
+	path := flags.String("file", "", "YAML or JSON policy document")
+	comment := flags.String("comment", "", "description of the change")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *path == "" {
+		return errors.New("-file is required")
+	}
+
+	data, err := os.ReadFile(*path)
+	if err != nil {
+		return err
+	}
+	document, err := services.ParsePolicyDocument(data)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	database, err := db.Connect(cfg)
+	if err != nil {
+		return err
+	}
+	policies := db.NewRepositories(database).Policies
+
+	latest, err := policies.LatestVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if document.Version <= latest {
+		return fmt.Errorf("policy version %d is not newer than the stored version %d", document.Version, latest)
+	}
+
+	err = policies.Create(ctx, &models.PolicySet{
+		Version:  document.Version,
+		Document: string(data),
+		Comment:  *comment,
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Stored policy version %d with %d policies", document.Version, len(document.Policies))
+	return nil
+}
+
+// revokeTokens bumps the revocation epoch, invalidating every token issued so far
+func revokeTokens(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("revoke-tokens", flag.ContinueOnError)
+	organization := flags.String("org", "", "only revoke tokens of this organization")
+	username := flags.String("admin", "", "username of the admin running the command, recorded in the audit log")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *username == "" {
+		return errors.New("-admin is required")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	redisClient := pkg.NewRedisClient(cfg)
+	database, err := db.Connect(cfg)
+	if err != nil {
+		return err
+	}
+	repos := db.NewRepositories(database)
+
+	admin, err := repos.Users.GetByUsername(ctx, *username)
+	if err != nil {
+		return fmt.Errorf("unknown admin %q: %w", *username, err)
+	}
+	if admin.Role != "admin" {
+		return fmt.Errorf("user %q is not an admin", *username)
+	}
+
+	hostname, _ := os.Hostname()
+	epochs := services.NewRevocationEpochService(
+		services.NewTokenRevocationStore(redisClient),
+		services.NewAuthLogService(repos.AuthLogs),
+		services.NewEventService(cfg),
+	)
+	epoch, err := epochs.Bump(ctx, admin.ID, *organization, services.ClientInfo{UserAgent: "cli@" + hostname})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Every token issued up to %s is now rejected", epoch.Format(time.RFC3339))
+	return nil
+}
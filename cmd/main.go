@@ -2,7 +2,10 @@
 package main
 
 import (
+	"context"
 	"github.com/SAP-2025/auth-service/internal/config"
+	"github.com/SAP-2025/auth-service/internal/db"
+	"github.com/SAP-2025/auth-service/internal/middleware"
 	"github.com/SAP-2025/auth-service/internal/routes"
 	"github.com/SAP-2025/auth-service/internal/services"
 	"github.com/SAP-2025/auth-service/pkg"
@@ -12,6 +15,8 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 func main() {
@@ -20,15 +25,112 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
+
+	if len(os.Args) > 1 {
+		if err := runCommand(cfg, os.Args[1], os.Args[2:]); err != nil {
+			log.Fatalf("%s: %v", os.Args[1], err)
+		}
+		return
+	}
+
 	redisClient := pkg.NewRedisClient(cfg)
 	log.Println("Connected to Redis successfully")
 
+	// Initialize Postgres
+	database, err := db.Connect(cfg)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	log.Println("Connected to Postgres successfully")
+	warnPendingMigrations(database)
+	repos := db.NewRepositories(database)
+
 	// Initialize stores and services
-	pkceStore := services.NewPKCEStore(redisClient)
-	authService := services.NewAuthService(pkceStore, cfg)
+	loginStates, err := services.NewLoginStateStore(redisClient, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize login state store: %v", err)
+	}
+	sessionService, err := services.NewSessionService(repos.Sessions, redisClient, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize session service: %v", err)
+	}
+	roleMapper, err := services.NewRoleMapper(cfg)
+	if err != nil {
+		log.Fatalf("Invalid role mapping: %v", err)
+	}
+	userService := services.NewUserService(repos, roleMapper, redisClient)
+	eventService := services.NewEventService(cfg)
+	authLogService := services.NewAuthLogService(repos.AuthLogs)
+	revokedTokens := services.NewTokenRevocationStore(redisClient)
+	keyStore, err := services.NewKeyStore(database, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize key store: %v", err)
+	}
+	keyManager, err := services.NewKeyManager(keyStore, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize key manager: %v", err)
+	}
+	if err := keyManager.Load(context.Background()); err != nil {
+		log.Fatalf("Failed to load signing keys: %v", err)
+	}
+	tokenService := services.NewTokenService(keyManager, cfg)
+	casdoorCerts := services.NewCasdoorCertManager(cfg)
+	if err := casdoorCerts.Load(context.Background()); err != nil {
+		log.Fatalf("Failed to load Casdoor certificates: %v", err)
+	}
+	authService := services.NewAuthService(loginStates, sessionService, userService, eventService, authLogService, revokedTokens, tokenService, casdoorCerts, cfg)
+	introspectionService := services.NewIntrospectionService(tokenService, sessionService, userService, revokedTokens, redisClient, cfg)
+	clientRegistry := services.NewClientRegistry(cfg)
+	epochService := services.NewRevocationEpochService(revokedTokens, authLogService, eventService)
+	bffSessions := services.NewBFFSessionStore(redisClient, authService, cfg)
+	if cfg.CSRFSecret() == "" {
+		log.Fatalf("security.csrf.secret or jwt.secret is required to sign CSRF tokens")
+	}
+	// Cookies that authenticate a request: the BFF session and the pending login
+	csrf := middleware.NewCSRFProtector(cfg.CSRFSecret(), cfg.Security.AllowedOrigins, bffSessions.CookieName(), config.LoginCookieName)
+	authz := middleware.NewAuthorizer(authService, authLogService, services.NewRolePermissions(cfg))
+	policySource, err := services.NewPolicySource(repos.Policies, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize policy source: %v", err)
+	}
+	policyEngine := services.NewPolicyEngine(policySource, cfg)
+	if err := policyEngine.Load(context.Background()); err != nil {
+		log.Fatalf("Failed to load authorization policies: %v", err)
+	}
+	userAdminService := services.NewUserAdminService(repos, roleMapper, userService, authService, eventService, authLogService, cfg)
+	authzService, err := services.NewAuthzService(policyEngine, authService, userService, authLogService, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize authorization service: %v", err)
+	}
+
+	// Start background workers
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	janitor := services.NewSessionJanitor(sessionService, eventService, cfg)
+	janitorDone := make(chan struct{})
+	go func() {
+		defer close(janitorDone)
+		janitor.Run(workerCtx)
+	}()
+	keyRotationDone := make(chan struct{})
+	go func() {
+		defer close(keyRotationDone)
+		keyManager.Run(workerCtx)
+	}()
+
+	casdoorCertsDone := make(chan struct{})
+	go func() {
+		defer close(casdoorCertsDone)
+		casdoorCerts.Run(workerCtx)
+	}()
+
+	policiesDone := make(chan struct{})
+	go func() {
+		defer close(policiesDone)
+		policyEngine.Run(workerCtx)
+	}()
 
 	// Setup routes
-	router := routes.SetupRoutes(authService)
+	router := routes.SetupRoutes(authService, keyManager, introspectionService, bffSessions, csrf, authz, epochService, authzService, userAdminService, clientRegistry, cfg)
 
 	// Create server
 	server := &http.Server{
@@ -53,7 +155,32 @@ func main() {
 	<-quit
 
 	log.Println("Server shutting down...")
-	// Add cleanup logic here if needed
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Server shutdown error: %v", err)
+	}
+
+	stopWorkers()
+	<-janitorDone
+	<-keyRotationDone
+	<-casdoorCertsDone
+	<-policiesDone
 
 	log.Println("Server stopped")
 }
+
+// warnPendingMigrations logs when the schema is behind the embedded migrations
+func warnPendingMigrations(database *gorm.DB) {
+	migrator, err := db.NewMigrator(database)
+	if err != nil {
+		log.Printf("Failed to load migrations: %v", err)
+		return
+	}
+	pending, err := migrator.Pending(context.Background())
+	if err != nil {
+		log.Printf("Failed to check schema migrations: %v", err)
+	} else if pending > 0 {
+		log.Printf("Warning: %d schema migrations are pending, run `auth-service migrate up`", pending)
+	}
+}
@@ -1,8 +1,16 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"github.com/casdoor/casdoor-go-sdk/casdoorsdk"
 	"github.com/spf13/viper"
+	"golang.org/x/crypto/hkdf"
+	"io"
+	"net/http"
+	"strings"
 	"time"
 )
 
@@ -18,17 +26,41 @@ type Config struct {
 			RedirectURI      string `mapstructure:"redirect_uri"`
 			OrganizationName string `mapstructure:"organization_name"`
 			ApplicationName  string `mapstructure:"application_name"`
-			Cert             string `mapstructure:"cert"`
+			// Path of a PEM file or inline PEM, may hold several certificates
+			Cert string `mapstructure:"cert"`
+			// Casdoor JWKS endpoint, defaults to <base_url>/.well-known/jwks when no cert is set
+			JWKSURL             string        `mapstructure:"jwks_url"`
+			CertRefreshInterval time.Duration `mapstructure:"cert_refresh_interval"`
+			// Expected iss of ID tokens, defaults to base_url
+			Issuer    string        `mapstructure:"issuer"`
+			ClockSkew time.Duration `mapstructure:"clock_skew"`
 		} `mapstructure:"casdoor"`
+		// Internal services allowed to call the introspection and revocation endpoints
+		Clients               []OAuthClient `mapstructure:"clients"`
+		IntrospectionCacheTTL time.Duration `mapstructure:"introspection_cache_ttl"`
 	} `mapstructure:"oauth2"`
 	JWT struct {
-		Secret             string `mapstructure:"secret"`
-		AccessTokenExpiry  string `mapstructure:"access_token_expiry"`
-		RefreshTokenExpiry string `mapstructure:"refresh_token_expiry"`
-		Issuer             string `mapstructure:"issuer"`
+		Secret             string   `mapstructure:"secret"`
+		AccessTokenExpiry  string   `mapstructure:"access_token_expiry"`
+		RefreshTokenExpiry string   `mapstructure:"refresh_token_expiry"`
+		Issuer             string   `mapstructure:"issuer"`
+		Audience           []string `mapstructure:"audience"`
+		Scopes             []string `mapstructure:"scopes"`
+		Signing            struct {
+			Algorithm        string        `mapstructure:"algorithm"` // RS256, ES256 or EdDSA
+			Storage          string        `mapstructure:"storage"`   // database or file
+			KeyDir           string        `mapstructure:"key_dir"`
+			EncryptionKey    string        `mapstructure:"encryption_key"` // encrypts private keys and Casdoor tokens in the database, derived from Secret by default
+			RotationInterval time.Duration `mapstructure:"rotation_interval"`
+			PublishAhead     time.Duration `mapstructure:"publish_ahead"` // how long a new key is published before it signs
+		} `mapstructure:"signing"`
 	} `mapstructure:"jwt"`
 	Database struct {
-		DSN string `mapstructure:"dsn"`
+		DSN             string        `mapstructure:"dsn"`
+		MaxOpenConns    int           `mapstructure:"max_open_conns"`
+		MaxIdleConns    int           `mapstructure:"max_idle_conns"`
+		ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime"`
+		ConnMaxIdleTime time.Duration `mapstructure:"conn_max_idle_time"`
 	} `mapstructure:"database"`
 	Redis struct {
 		Addr     string `mapstructure:"addr"`
@@ -42,7 +74,35 @@ type Config struct {
 	Session struct {
 		MaxConcurrentSessions int           `mapstructure:"max_concurrent_sessions"`
 		CleanupInterval       time.Duration `mapstructure:"cleanup_interval"`
+		CleanupBatchSize      int           `mapstructure:"cleanup_batch_size"`
+		// reject, evict_oldest or evict_lru, applied when the limit is reached
+		EvictionPolicy string `mapstructure:"eviction_policy"`
+		// Per role overrides of the limit and policy
+		Roles map[string]SessionLimit `mapstructure:"roles"`
+		// redis or memory (single instance only), keeps logins pending until the callback
+		LoginStateStore string        `mapstructure:"login_state_store"`
+		LoginStateTTL   time.Duration `mapstructure:"login_state_ttl"`
 	} `mapstructure:"session"`
+	// Translates Casdoor roles, groups and email domains into local roles
+	RoleMapping struct {
+		// Evaluated in order, the first matching rule decides the role
+		Rules []RoleRule `mapstructure:"rules"`
+		// Role of users no rule matches, defaults to student
+		Default string `mapstructure:"default"`
+		// Reject users no rule matches instead of giving them the default role
+		DenyUnmapped bool `mapstructure:"deny_unmapped"`
+		// Permissions granted to each local role, replacing the built-in defaults of a listed role
+		Permissions map[string][]string `mapstructure:"permissions"`
+	} `mapstructure:"role_mapping"`
+	// Policy engine answering authorization checks of other services
+	Authorization struct {
+		// file or database, the highest stored version is active
+		PolicySource   string        `mapstructure:"policy_source"`
+		PolicyFile     string        `mapstructure:"policy_file"`
+		ReloadInterval time.Duration `mapstructure:"reload_interval"`
+		// all, deny or none; decisions are written to the audit log
+		DecisionLog string `mapstructure:"decision_log"`
+	} `mapstructure:"authorization"`
 	Security struct {
 		RateLimit struct {
 			LoginAttempts int           `mapstructure:"login_attempts"`
@@ -53,9 +113,51 @@ type Config struct {
 			SameSite string `mapstructure:"same_site"`
 			HttpOnly bool   `mapstructure:"http_only"`
 		} `mapstructure:"cookie"`
+		// Frontend origins allowed to send cookie authenticated requests and
+		// to be redirected to after login
+		AllowedOrigins []string `mapstructure:"allowed_origins"`
+		// Frontend page the callback redirects to with ?error=<code> when a login fails
+		LoginErrorURL string `mapstructure:"login_error_url"`
+		CSRF          struct {
+			Secret string `mapstructure:"secret"` // derived from jwt.secret by default
+		} `mapstructure:"csrf"`
+		// Backend-for-frontend mode: tokens stay in Redis and the browser only
+		// gets an opaque session cookie
+		BFF struct {
+			Enabled    bool   `mapstructure:"enabled"`
+			CookieName string `mapstructure:"cookie_name"`
+		} `mapstructure:"bff"`
 	} `mapstructure:"security"`
 }
 
+type OAuthClient struct {
+	ID     string `mapstructure:"client_id"`
+	Secret string `mapstructure:"client_secret"`
+	Name   string `mapstructure:"name"`
+}
+
+// RoleRule grants Role to Casdoor users matching every field that is set
+type RoleRule struct {
+	Role        string `mapstructure:"role"`
+	CasdoorRole string `mapstructure:"casdoor_role"`
+	// Group path such as org/staff, also matches its subgroups
+	Group       string `mapstructure:"group"`
+	EmailDomain string `mapstructure:"email_domain"`
+	Tag         string `mapstructure:"tag"`
+}
+
+// Session eviction policies
+const (
+	EvictionPolicyReject      = "reject"
+	EvictionPolicyEvictOldest = "evict_oldest"
+	EvictionPolicyEvictLRU    = "evict_lru"
+)
+
+type SessionLimit struct {
+	MaxConcurrentSessions int    `mapstructure:"max_concurrent_sessions"`
+	EvictionPolicy        string `mapstructure:"eviction_policy"`
+}
+
 func Load() (*Config, error) {
 	viper.SetConfigFile("config.yaml")
 	viper.SetConfigType("yaml")
@@ -68,20 +170,181 @@ func Load() (*Config, error) {
 	if err := viper.Unmarshal(&cfg); err != nil {
 		return nil, err
 	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
 
 	return &cfg, nil
 }
 
-func NewCasdoorClient(cfg *Config) *casdoorsdk.Client {
-	// cert is file, config should be a path to the certificate file
-	if cfg.OAuth2.Casdoor.Cert != "" {
+// validate rejects settings that would otherwise only fail once a request uses them
+func (c *Config) validate() error {
+	// Also the default audience of access tokens
+	if c.JWT.Issuer == "" {
+		return errors.New("jwt.issuer is required")
+	}
+	if !validEvictionPolicy(c.Session.EvictionPolicy) {
+		return fmt.Errorf("session.eviction_policy: unknown policy %q", c.Session.EvictionPolicy)
+	}
+	for role, limit := range c.Session.Roles {
+		if !validEvictionPolicy(limit.EvictionPolicy) {
+			return fmt.Errorf("session.roles.%s.eviction_policy: unknown policy %q", role, limit.EvictionPolicy)
+		}
+	}
+	return nil
+}
+
+// validEvictionPolicy reports whether policy is a known eviction policy or
+// empty, which falls back to the default
+func validEvictionPolicy(policy string) bool {
+	switch policy {
+	case "", EvictionPolicyReject, EvictionPolicyEvictOldest, EvictionPolicyEvictLRU:
+		return true
+	}
+	return false
+}
+
+// AccessTokenTTL returns the lifetime of an access token, defaulting to 15 minutes
+func (c *Config) AccessTokenTTL() time.Duration {
+	return parseDuration(c.JWT.AccessTokenExpiry, 15*time.Minute)
+}
+
+// TokenScopes returns the scopes granted to issued tokens
+func (c *Config) TokenScopes() []string {
+	if len(c.JWT.Scopes) == 0 {
+		return []string{"openid", "profile", "email"}
+	}
+	return c.JWT.Scopes
+}
+
+// TokenAudience returns the audience of issued access tokens, defaulting to
+// the issuer so tokens are never accepted by every service
+func (c *Config) TokenAudience() []string {
+	if len(c.JWT.Audience) == 0 {
+		return []string{c.JWT.Issuer}
+	}
+	return c.JWT.Audience
+}
+
+// EncryptionKey returns the secret data stored in the database is encrypted with
+func (c *Config) EncryptionKey() string {
+	if c.JWT.Signing.EncryptionKey != "" {
+		return c.JWT.Signing.EncryptionKey
+	}
+	return c.deriveSecret("encryption")
+}
+
+// RefreshTokenTTL returns the lifetime of a refresh token, defaulting to 7 days
+func (c *Config) RefreshTokenTTL() time.Duration {
+	return parseDuration(c.JWT.RefreshTokenExpiry, 7*24*time.Hour)
+}
+
+// LoginStateTTL returns how long a started login can be completed, defaulting to 10 minutes
+func (c *Config) LoginStateTTL() time.Duration {
+	if c.Session.LoginStateTTL <= 0 {
+		return 10 * time.Minute
+	}
+	return c.Session.LoginStateTTL
+}
+
+// SessionLimitFor returns the concurrent session limit of a role, falling back
+// to the global limit and policy for anything the role does not override.
+// A limit of 0 means unlimited.
+func (c *Config) SessionLimitFor(role string) SessionLimit {
+	limit := SessionLimit{
+		MaxConcurrentSessions: c.Session.MaxConcurrentSessions,
+		EvictionPolicy:        c.Session.EvictionPolicy,
+	}
+
+	if override, ok := c.Session.Roles[role]; ok {
+		if override.MaxConcurrentSessions > 0 {
+			limit.MaxConcurrentSessions = override.MaxConcurrentSessions
+		}
+		if override.EvictionPolicy != "" {
+			limit.EvictionPolicy = override.EvictionPolicy
+		}
+	}
+
+	if limit.EvictionPolicy == "" {
+		limit.EvictionPolicy = EvictionPolicyEvictOldest
+	}
+
+	return limit
+}
+
+// CookieSameSite returns the SameSite mode of Security.Cookie, defaulting to Lax
+func (c *Config) CookieSameSite() http.SameSite {
+	switch strings.ToLower(c.Security.Cookie.SameSite) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
+
+// CSRFSecret returns the key CSRF tokens are signed with
+func (c *Config) CSRFSecret() string {
+	if c.Security.CSRF.Secret == "" {
+		return c.deriveSecret("csrf")
+	}
+	return c.Security.CSRF.Secret
+}
 
+// deriveSecret derives a key for one purpose from JWT.Secret with HKDF, so a
+// fallback never uses the same key for two purposes
+func (c *Config) deriveSecret(label string) string {
+	if c.JWT.Secret == "" {
+		return ""
 	}
+
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, []byte(c.JWT.Secret), nil, []byte(label)), key); err != nil {
+		panic(err) // unreachable, HKDF-SHA256 yields up to 8160 bytes
+	}
+	return hex.EncodeToString(key)
+}
+
+// LoginCookieName is the cookie holding the state of a pending login
+const LoginCookieName = "session_id"
+
+// BFFCookieName returns the name of the BFF session cookie
+func (c *Config) BFFCookieName() string {
+	if c.Security.BFF.CookieName == "" {
+		return "auth_session"
+	}
+	return c.Security.BFF.CookieName
+}
+
+func parseDuration(value string, fallback time.Duration) time.Duration {
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return fallback
+	}
+	return d
+}
+
+// CasdoorJWKSURL returns the Casdoor JWKS endpoint certificates are fetched
+// from, empty when only the configured cert is used
+func (c *Config) CasdoorJWKSURL() string {
+	if c.OAuth2.Casdoor.JWKSURL != "" {
+		return c.OAuth2.Casdoor.JWKSURL
+	}
+	if c.OAuth2.Casdoor.Cert != "" {
+		return ""
+	}
+	return strings.TrimSuffix(c.OAuth2.Casdoor.BaseURL, "/") + "/.well-known/jwks"
+}
+
+// NewCasdoorClient returns a client for the Casdoor API. It carries no
+// certificate, tokens are verified by services.CasdoorCertManager.
+func NewCasdoorClient(cfg *Config) *casdoorsdk.Client {
 	client := casdoorsdk.NewClient(
 		cfg.OAuth2.Casdoor.BaseURL,
 		cfg.OAuth2.Casdoor.ClientID,
 		cfg.OAuth2.Casdoor.ClientSecret,
-		cfg.OAuth2.Casdoor.RedirectURI,
+		"",
 		cfg.OAuth2.Casdoor.OrganizationName,
 		cfg.OAuth2.Casdoor.ApplicationName,
 	)
@@ -0,0 +1,29 @@
+package db
+
+import (
+	"context"
+	"github.com/SAP-2025/auth-service/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type authLogRepository struct {
+	db *gorm.DB
+}
+
+func (r *authLogRepository) Create(ctx context.Context, entry *models.AuthLog) error {
+	// Success has a database default of true, so false must be written explicitly
+	return r.db.WithContext(ctx).
+		Select("UserID", "EventType", "IPAddress", "UserAgent", "Success", "ErrorMessage", "Details", "CreatedAt", "UpdatedAt").
+		Create(entry).Error
+}
+
+func (r *authLogRepository) ListByUser(ctx context.Context, userID uint, limit int) ([]models.AuthLog, error) {
+	var entries []models.AuthLog
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&entries).Error
+	return entries, err
+}
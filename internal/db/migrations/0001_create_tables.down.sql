@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS signing_keys;
+DROP TABLE IF EXISTS auth_logs;
+DROP TABLE IF EXISTS user_sessions;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,63 @@
+-- IF NOT EXISTS adopts databases created by the former AutoMigrate
+CREATE TABLE IF NOT EXISTS users (
+    id              BIGSERIAL PRIMARY KEY,
+    created_at      TIMESTAMPTZ,
+    updated_at      TIMESTAMPTZ,
+    deleted_at      TIMESTAMPTZ,
+    casdoor_user_id TEXT NOT NULL UNIQUE,
+    username        TEXT NOT NULL UNIQUE,
+    email           TEXT NOT NULL UNIQUE,
+    name            TEXT NOT NULL,
+    avatar_url      TEXT,
+    role            TEXT CONSTRAINT chk_users_role CHECK (role IN ('student', 'teacher', 'admin', 'proctor')),
+    organization    TEXT,
+    is_active       BOOLEAN DEFAULT TRUE,
+    last_login_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS user_sessions (
+    id                    UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id               BIGINT REFERENCES users (id) ON DELETE CASCADE,
+    refresh_token_hash    TEXT NOT NULL,
+    expires_at            TIMESTAMPTZ NOT NULL,
+    created_at            TIMESTAMPTZ DEFAULT NOW(),
+    last_used_at          TIMESTAMPTZ DEFAULT NOW(),
+    user_agent            TEXT,
+    ip_address            BYTEA,
+    access_token_id       TEXT,
+    casdoor_access_token  TEXT,
+    casdoor_refresh_token TEXT
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_sessions_refresh_token_hash ON user_sessions (refresh_token_hash);
+
+CREATE TABLE IF NOT EXISTS auth_logs (
+    id            BIGSERIAL PRIMARY KEY,
+    created_at    TIMESTAMPTZ,
+    updated_at    TIMESTAMPTZ,
+    deleted_at    TIMESTAMPTZ,
+    user_id       BIGINT,
+    event_type    TEXT NOT NULL,
+    ip_address    BYTEA,
+    user_agent    TEXT,
+    success       BOOLEAN DEFAULT TRUE,
+    error_message TEXT,
+    details       TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_logs_deleted_at ON auth_logs (deleted_at);
+
+CREATE TABLE IF NOT EXISTS signing_keys (
+    id          TEXT PRIMARY KEY,
+    algorithm   TEXT NOT NULL,
+    private_key BYTEA NOT NULL,
+    public_key  TEXT NOT NULL,
+    not_before  TIMESTAMPTZ NOT NULL,
+    not_after   TIMESTAMPTZ NOT NULL,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    created_at  TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_signing_keys_expires_at ON signing_keys (expires_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_auth_logs_user_id_created_at;
+DROP INDEX IF EXISTS idx_user_sessions_expires_at;
+DROP INDEX IF EXISTS idx_user_sessions_user_id;
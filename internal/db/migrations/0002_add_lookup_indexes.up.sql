@@ -0,0 +1,4 @@
+-- Sessions are listed per user, audit logs per user newest first
+CREATE INDEX IF NOT EXISTS idx_user_sessions_user_id ON user_sessions (user_id);
+CREATE INDEX IF NOT EXISTS idx_user_sessions_expires_at ON user_sessions (expires_at);
+CREATE INDEX IF NOT EXISTS idx_auth_logs_user_id_created_at ON auth_logs (user_id, created_at);
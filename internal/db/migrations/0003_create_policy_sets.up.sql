@@ -0,0 +1,7 @@
+-- Authorization policies, every change is stored as a new version
+CREATE TABLE IF NOT EXISTS policy_sets (
+    version    INTEGER PRIMARY KEY CHECK (version > 0),
+    document   TEXT NOT NULL,
+    comment    TEXT,
+    created_at TIMESTAMPTZ
+);
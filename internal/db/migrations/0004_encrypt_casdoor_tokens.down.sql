@@ -0,0 +1,2 @@
+-- The deleted sessions cannot be restored
+SELECT 1;
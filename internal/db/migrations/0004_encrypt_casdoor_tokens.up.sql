@@ -0,0 +1,3 @@
+-- Casdoor tokens are now stored encrypted; sessions created before hold them
+-- in plaintext and cannot be decrypted, so those users sign in again
+DELETE FROM user_sessions;
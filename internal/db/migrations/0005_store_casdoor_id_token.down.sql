@@ -0,0 +1,2 @@
+UPDATE user_sessions SET casdoor_id_token = NULL;
+ALTER TABLE user_sessions RENAME COLUMN casdoor_id_token TO casdoor_access_token;
@@ -0,0 +1,3 @@
+-- Casdoor logout takes the ID token as hint, the access token is no longer kept
+ALTER TABLE user_sessions RENAME COLUMN casdoor_access_token TO casdoor_id_token;
+UPDATE user_sessions SET casdoor_id_token = NULL;
@@ -0,0 +1,32 @@
+package db
+
+import (
+	"context"
+	"github.com/SAP-2025/auth-service/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type policyRepository struct {
+	db *gorm.DB
+}
+
+func (r *policyRepository) Latest(ctx context.Context) (*models.PolicySet, error) {
+	var set models.PolicySet
+	if err := r.db.WithContext(ctx).Order("version DESC").First(&set).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &set, nil
+}
+
+func (r *policyRepository) LatestVersion(ctx context.Context) (int, error) {
+	var version int
+	err := r.db.WithContext(ctx).Model(&models.PolicySet{}).
+		Select("COALESCE(MAX(version), 0)").
+		Scan(&version).Error
+	return version, err
+}
+
+func (r *policyRepository) Create(ctx context.Context, set *models.PolicySet) error {
+	return r.db.WithContext(ctx).Create(set).Error
+}
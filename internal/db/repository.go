@@ -1 +1,100 @@
 package db
+
+import (
+	"context"
+	"errors"
+	"github.com/SAP-2025/auth-service/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrNotFound = errors.New("record not found")
+
+// UserFilter selects a page of users, empty fields match every user
+type UserFilter struct {
+	// Substring of the username, name or email
+	Query        string
+	Role         string
+	Organization string
+	Active       *bool
+	Offset       int
+	Limit        int
+}
+
+type UserRepository interface {
+	GetByID(ctx context.Context, id uint) (*models.User, error)
+	GetByCasdoorUserID(ctx context.Context, casdoorUserID string) (*models.User, error)
+	// GetByCasdoorUserIDForUpdate locks the row until the transaction ends
+	GetByCasdoorUserIDForUpdate(ctx context.Context, casdoorUserID string) (*models.User, error)
+	GetByUsername(ctx context.Context, username string) (*models.User, error)
+	// List returns a page of the users matching filter, ordered by ID, and their total count
+	List(ctx context.Context, filter UserFilter) ([]models.User, int64, error)
+	Create(ctx context.Context, user *models.User) error
+	Update(ctx context.Context, id uint, updates map[string]interface{}) error
+	// IsTaken reports whether a user other than excludeID has value in column
+	IsTaken(ctx context.Context, column, value string, excludeID uint) (bool, error)
+}
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *models.UserSession) error
+	Get(ctx context.Context, userID uint, sessionID string) (*models.UserSession, error)
+	GetByRefreshTokenHash(ctx context.Context, hash string) (*models.UserSession, error)
+	ListActive(ctx context.Context, userID uint) ([]models.UserSession, error)
+	// UpdateIfHash applies updates only while the session holds refreshTokenHash
+	UpdateIfHash(ctx context.Context, sessionID uuid.UUID, refreshTokenHash string, updates map[string]interface{}) (int64, error)
+	Delete(ctx context.Context, userID uint, sessionID string) (int64, error)
+	DeleteByID(ctx context.Context, sessionID uuid.UUID) error
+	FindExpired(ctx context.Context, before time.Time, limit int) ([]models.UserSession, error)
+	DeleteByIDs(ctx context.Context, ids []uuid.UUID) error
+}
+
+type AuthLogRepository interface {
+	Create(ctx context.Context, entry *models.AuthLog) error
+	// ListByUser returns the latest entries of a user, newest first
+	ListByUser(ctx context.Context, userID uint, limit int) ([]models.AuthLog, error)
+}
+
+type PolicyRepository interface {
+	// Latest returns the highest policy set version
+	Latest(ctx context.Context) (*models.PolicySet, error)
+	// LatestVersion returns the highest version, 0 when no policies are stored
+	LatestVersion(ctx context.Context) (int, error)
+	Create(ctx context.Context, set *models.PolicySet) error
+}
+
+// Repositories groups the repositories sharing one connection or transaction
+type Repositories struct {
+	Users    UserRepository
+	Sessions SessionRepository
+	AuthLogs AuthLogRepository
+	Policies PolicyRepository
+
+	db *gorm.DB
+}
+
+func NewRepositories(db *gorm.DB) *Repositories {
+	return &Repositories{
+		Users:    &userRepository{db: db},
+		Sessions: &sessionRepository{db: db},
+		AuthLogs: &authLogRepository{db: db},
+		Policies: &policyRepository{db: db},
+		db:       db,
+	}
+}
+
+// Transaction runs fn with repositories bound to a single transaction, which
+// is committed when fn returns nil
+func (r *Repositories) Transaction(ctx context.Context, fn func(tx *Repositories) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(NewRepositories(tx))
+	})
+}
+
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"github.com/SAP-2025/auth-service/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+func (r *sessionRepository) Create(ctx context.Context, session *models.UserSession) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+func (r *sessionRepository) Get(ctx context.Context, userID uint, sessionID string) (*models.UserSession, error) {
+	var session models.UserSession
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", sessionID, userID).
+		First(&session).Error
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &session, nil
+}
+
+func (r *sessionRepository) GetByRefreshTokenHash(ctx context.Context, hash string) (*models.UserSession, error) {
+	var session models.UserSession
+	err := r.db.WithContext(ctx).Where("refresh_token_hash = ?", hash).First(&session).Error
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &session, nil
+}
+
+func (r *sessionRepository) ListActive(ctx context.Context, userID uint) ([]models.UserSession, error) {
+	var sessions []models.UserSession
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *sessionRepository) UpdateIfHash(ctx context.Context, sessionID uuid.UUID, refreshTokenHash string, updates map[string]interface{}) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&models.UserSession{}).
+		Where("id = ? AND refresh_token_hash = ?", sessionID, refreshTokenHash).
+		Updates(updates)
+	return result.RowsAffected, result.Error
+}
+
+func (r *sessionRepository) Delete(ctx context.Context, userID uint, sessionID string) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", sessionID, userID).
+		Delete(&models.UserSession{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *sessionRepository) DeleteByID(ctx context.Context, sessionID uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("id = ?", sessionID).Delete(&models.UserSession{}).Error
+}
+
+func (r *sessionRepository) FindExpired(ctx context.Context, before time.Time, limit int) ([]models.UserSession, error) {
+	var expired []models.UserSession
+	err := r.db.WithContext(ctx).
+		Select("id", "user_id").
+		Where("expires_at <= ?", before).
+		Limit(limit).
+		Find(&expired).Error
+	return expired, err
+}
+
+func (r *sessionRepository) DeleteByIDs(ctx context.Context, ids []uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("id IN ?", ids).Delete(&models.UserSession{}).Error
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	custommiddleware "github.com/SAP-2025/auth-service/internal/middleware"
+	"github.com/SAP-2025/auth-service/internal/services"
+	"log"
+	"net/http"
+	"time"
+)
+
+type AdminHandler struct {
+	authService *services.AuthService
+	epochs      *services.RevocationEpochService
+}
+
+func NewAdminHandler(authService *services.AuthService, epochs *services.RevocationEpochService) *AdminHandler {
+	return &AdminHandler{authService: authService, epochs: epochs}
+}
+
+type RevocationEpochRequest struct {
+	Organization string `json:"organization"`
+}
+
+type RevocationEpochResponse struct {
+	Organization string    `json:"organization,omitempty"`
+	Epoch        time.Time `json:"epoch"`
+}
+
+// BumpRevocationEpoch invalidates every token issued so far, globally or for
+// the organization given in the body
+func (h *AdminHandler) BumpRevocationEpoch(w http.ResponseWriter, r *http.Request) {
+	admin, ok := custommiddleware.CurrentUserFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req RevocationEpochRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	epoch, err := h.epochs.Bump(r.Context(), admin.ID, req.Organization, clientInfo(r))
+	if err != nil {
+		log.Printf("Revocation epoch error: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to bump revocation epoch")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, RevocationEpochResponse{
+		Organization: req.Organization,
+		Epoch:        epoch,
+	})
+}
@@ -2,18 +2,29 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"github.com/SAP-2025/auth-service/internal/config"
+	custommiddleware "github.com/SAP-2025/auth-service/internal/middleware"
 	"github.com/SAP-2025/auth-service/internal/services"
 	"log"
 	"net/http"
+	"net/url"
 	"strings"
+	"time"
 )
 
 type AuthHandler struct {
 	authService *services.AuthService
+	bffSessions *services.BFFSessionStore
+	cfg         *config.Config
 }
 
-func NewAuthHandler(authService *services.AuthService) *AuthHandler {
-	return &AuthHandler{authService: authService}
+func NewAuthHandler(authService *services.AuthService, bffSessions *services.BFFSessionStore, cfg *config.Config) *AuthHandler {
+	return &AuthHandler{
+		authService: authService,
+		bffSessions: bffSessions,
+		cfg:         cfg,
+	}
 }
 
 type ErrorResponse struct {
@@ -33,6 +44,18 @@ type ProfileResponse struct {
 	User interface{} `json:"user"`
 }
 
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// BFFSessionResponse replaces the token response in BFF mode, the tokens
+// never leave the server
+type BFFSessionResponse struct {
+	SessionID string             `json:"session_id"`
+	ExpiresIn int64              `json:"expires_in"`
+	User      *services.UserInfo `json:"user,omitempty"`
+}
+
 // writeJSON helper function
 func writeJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
@@ -54,74 +77,311 @@ func getCookie(r *http.Request, name string) (string, error) {
 	return cookie.Value, nil
 }
 
-// setCookie helper function
-func setCookie(w http.ResponseWriter, name, value string, maxAge int) {
-	cookie := &http.Cookie{
+// setCookie sets an HttpOnly cookie with the Secure attribute of Security.Cookie
+func (h *AuthHandler) setCookie(w http.ResponseWriter, name, value string, maxAge int, sameSite http.SameSite) {
+	http.SetCookie(w, &http.Cookie{
 		Name:     name,
 		Value:    value,
 		MaxAge:   maxAge,
 		Path:     "/",
+		Secure:   h.cfg.Security.Cookie.Secure,
 		HttpOnly: true,
-		SameSite: http.SameSiteStrictMode,
+		SameSite: sameSite,
+	})
+}
+
+// setLoginCookie sets the login state cookie. It is always Lax whatever
+// Security.Cookie says, a Strict cookie would not come back on the redirect
+// from Casdoor.
+func (h *AuthHandler) setLoginCookie(w http.ResponseWriter, value string, maxAge int) {
+	h.setCookie(w, config.LoginCookieName, value, maxAge, http.SameSiteLaxMode)
+}
+
+// setSessionCookie sets the BFF session cookie with the SameSite mode of Security.Cookie
+func (h *AuthHandler) setSessionCookie(w http.ResponseWriter, value string, maxAge int) {
+	h.setCookie(w, h.bffSessions.CookieName(), value, maxAge, h.cfg.CookieSameSite())
+}
+
+// bearerToken helper function
+func bearerToken(r *http.Request) string {
+	authHeader := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		return ""
 	}
-	http.SetCookie(w, cookie)
+	return authHeader[7:]
+}
+
+// clientInfo helper function
+func clientInfo(r *http.Request) services.ClientInfo {
+	return custommiddleware.ClientInfo(r)
 }
 
-// Login handler
+// Login handler. With return_to the browser is redirected to Casdoor right
+// away, otherwise the login URL is returned as JSON.
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
-	loginResp, err := h.authService.GetLoginURL()
-	if err != nil {
+	returnTo := r.URL.Query().Get("return_to")
+	loginResp, err := h.authService.GetLoginURL(r.Context(), returnTo)
+	if errors.Is(err, services.ErrInvalidReturnTo) || errors.Is(err, services.ErrReturnToUnsupported) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	} else if err != nil {
 		log.Printf("Login error: %v", err)
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
 	// Set session cookie
-	setCookie(w, "session_id", loginResp.SessionID, 600)
+	h.setLoginCookie(w, loginResp.SessionID, int(h.cfg.LoginStateTTL().Seconds()))
+
+	if returnTo != "" {
+		http.Redirect(w, r, loginResp.LoginURL, http.StatusFound)
+		return
+	}
 
 	writeJSON(w, http.StatusOK, loginResp)
 }
 
-// Callback handler
+// Login error codes passed to the frontend error page
+const (
+	loginErrorAccessDenied   = "access_denied"
+	loginErrorInvalidSession = "invalid_session"
+	loginErrorExchangeFailed = "exchange_failed"
+	loginErrorInvalidIDToken = "invalid_id_token"
+	loginErrorSessionLimit   = "session_limit_reached"
+	loginErrorUserConflict   = "account_conflict"
+	loginErrorRoleUnmapped   = "role_unmapped"
+	loginErrorUserInactive   = "account_inactive"
+	loginErrorServerError    = "server_error"
+)
+
+// Callback handler. Logins started with return_to end with a redirect to it,
+// or to the configured error page when they fail.
 func (h *AuthHandler) Callback(w http.ResponseWriter, r *http.Request) {
 	code := r.URL.Query().Get("code")
 	state := r.URL.Query().Get("state")
 
-	if code == "" || state == "" {
-		writeError(w, http.StatusBadRequest, "Missing code or state")
+	// Verify session cookie matches state
+	sessionID, err := getCookie(r, config.LoginCookieName)
+	if err != nil || state == "" || sessionID != state {
+		h.callbackError(w, r, nil, http.StatusBadRequest, loginErrorInvalidSession, "Invalid session")
 		return
 	}
 
-	// Verify session cookie matches state
-	sessionID, err := getCookie(r, "session_id")
-	if err != nil || sessionID != state {
-		writeError(w, http.StatusBadRequest, "Invalid session")
+	loginState, err := h.authService.ConsumeLoginState(r.Context(), state)
+	if err != nil {
+		log.Printf("Callback error: %v", err)
+		h.callbackError(w, r, nil, http.StatusBadRequest, loginErrorInvalidSession, "Invalid session")
+		return
+	}
+
+	// Clear session cookie
+	h.setLoginCookie(w, "", -1)
+
+	// The user declined or Casdoor could not authenticate them
+	if idpError := r.URL.Query().Get("error"); idpError != "" {
+		log.Printf("Callback error from identity provider: %s", idpError)
+		errorCode := loginErrorExchangeFailed
+		if idpError == loginErrorAccessDenied {
+			errorCode = loginErrorAccessDenied
+		}
+		h.callbackError(w, r, loginState, http.StatusUnauthorized, errorCode, "Login failed: "+idpError)
+		return
+	}
+
+	if code == "" {
+		h.callbackError(w, r, loginState, http.StatusBadRequest, loginErrorExchangeFailed, "Missing code or state")
 		return
 	}
 
 	// Exchange code for token
-	callbackResp, err := h.authService.ExchangeCode(code, state)
-	if err != nil {
+	callbackResp, err := h.authService.ExchangeCode(r.Context(), code, loginState, clientInfo(r))
+	var idTokenErr *services.IDTokenError
+	if errors.Is(err, services.ErrSessionLimitReached) {
+		h.callbackError(w, r, loginState, http.StatusConflict, loginErrorSessionLimit, err.Error())
+		return
+	} else if errors.Is(err, services.ErrUserConflict) {
+		h.callbackError(w, r, loginState, http.StatusConflict, loginErrorUserConflict, "Account conflicts with an existing user")
+		return
+	} else if errors.Is(err, services.ErrUserInactive) {
+		h.callbackError(w, r, loginState, http.StatusForbidden, loginErrorUserInactive, "Account is deactivated")
+		return
+	} else if errors.Is(err, services.ErrRoleUnmapped) {
+		h.callbackError(w, r, loginState, http.StatusForbidden, loginErrorRoleUnmapped, "No role is granted to this account")
+		return
+	} else if errors.As(err, &idTokenErr) {
+		h.callbackError(w, r, loginState, http.StatusUnauthorized, loginErrorInvalidIDToken, idTokenErr.Code)
+		return
+	} else if err != nil {
 		log.Printf("Callback error: %v", err)
-		writeError(w, http.StatusBadRequest, err.Error())
+		h.callbackError(w, r, loginState, http.StatusBadRequest, loginErrorExchangeFailed, err.Error())
 		return
 	}
 
-	// Clear session cookie
-	setCookie(w, "session_id", "", -1)
+	if h.bffSessions.Enabled() {
+		cookie, err := h.bffSessions.Create(r.Context(), callbackResp)
+		if err != nil {
+			log.Printf("BFF session error: %v", err)
+			h.callbackError(w, r, loginState, http.StatusInternalServerError, loginErrorServerError, "Failed to create session")
+			return
+		}
+
+		h.setSessionCookie(w, cookie, int(h.bffSessions.TTL().Seconds()))
+		if loginState.ReturnTo != "" {
+			http.Redirect(w, r, loginState.ReturnTo, http.StatusFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, BFFSessionResponse{
+			SessionID: callbackResp.SessionID,
+			ExpiresIn: callbackResp.ExpiresIn,
+			User:      callbackResp.User,
+		})
+		return
+	}
 
 	writeJSON(w, http.StatusOK, callbackResp)
 }
 
+// callbackError ends a failed callback. Browsers sent by a redirect login go to
+// the error page, or back to return_to when none is configured; anything else
+// gets a JSON error.
+func (h *AuthHandler) callbackError(w http.ResponseWriter, r *http.Request, loginState *services.LoginState, status int, code, message string) {
+	target := h.cfg.Security.LoginErrorURL
+	if target == "" && loginState != nil {
+		target = loginState.ReturnTo
+	}
+	if target == "" || (loginState != nil && loginState.ReturnTo == "") {
+		writeError(w, status, message)
+		return
+	}
+
+	errorURL, err := url.Parse(target)
+	if err != nil {
+		log.Printf("Invalid login error URL %q: %v", target, err)
+		writeError(w, status, message)
+		return
+	}
+	query := errorURL.Query()
+	query.Set("error", code)
+	errorURL.RawQuery = query.Encode()
+
+	http.Redirect(w, r, errorURL.String(), http.StatusFound)
+}
+
+// Refresh access token and rotate the refresh token
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := getCookie(r, h.bffSessions.CookieName()); err == nil && h.bffSessions.Enabled() {
+		h.refreshSession(w, r, cookie)
+		return
+	}
+
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		writeError(w, http.StatusBadRequest, "Missing refresh token")
+		return
+	}
+
+	refreshResp, err := h.authService.RefreshToken(r.Context(), req.RefreshToken, clientInfo(r))
+	if err != nil {
+		log.Printf("Refresh error: %v", err)
+		if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrRefreshTokenExpired) ||
+			errors.Is(err, services.ErrRefreshTokenReused) || errors.Is(err, services.ErrTokenRevoked) ||
+			errors.Is(err, services.ErrRoleUnmapped) || errors.Is(err, services.ErrUserInactive) {
+			writeError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "Failed to refresh token")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, refreshResp)
+}
+
+// refreshSession renews the tokens of a BFF session
+func (h *AuthHandler) refreshSession(w http.ResponseWriter, r *http.Request, cookie string) {
+	session, err := h.bffSessions.Refresh(r.Context(), cookie, clientInfo(r))
+	if err != nil {
+		log.Printf("Refresh error: %v", err)
+		if errors.Is(err, services.ErrBFFSessionNotFound) || errors.Is(err, services.ErrInvalidRefreshToken) ||
+			errors.Is(err, services.ErrRefreshTokenExpired) || errors.Is(err, services.ErrRefreshTokenReused) ||
+			errors.Is(err, services.ErrTokenRevoked) || errors.Is(err, services.ErrRoleUnmapped) ||
+			errors.Is(err, services.ErrUserInactive) {
+			h.setSessionCookie(w, "", -1)
+			writeError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "Failed to refresh token")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, BFFSessionResponse{
+		SessionID: session.SessionID,
+		ExpiresIn: int64(time.Until(session.ExpiresAt).Seconds()),
+	})
+}
+
+// Logout ends the current session. Logging out of a session that already
+// ended succeeds, so clients can retry safely.
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	token := bearerToken(r)
+	bffLogout := false
+	var bffSession *services.BFFSession
+	if cookie, err := getCookie(r, h.bffSessions.CookieName()); err == nil && h.bffSessions.Enabled() {
+		session, err := h.bffSessions.Delete(r.Context(), cookie)
+		if err != nil && !errors.Is(err, services.ErrBFFSessionNotFound) {
+			log.Printf("Logout error: %v", err)
+			writeError(w, http.StatusInternalServerError, "Failed to logout")
+			return
+		}
+		bffLogout = true
+		bffSession = session
+		h.setSessionCookie(w, "", -1)
+	}
+	if token == "" && bffSession != nil {
+		token = bffSession.AccessToken
+	}
+
+	if token == "" && !bffLogout {
+		writeError(w, http.StatusUnauthorized, "Missing access token")
+		return
+	}
+
+	var err error
+	if token != "" {
+		err = h.authService.Logout(r.Context(), token, services.LogoutReasonUser, clientInfo(r))
+	}
+	if errors.Is(err, services.ErrInvalidAccessToken) && bffSession != nil {
+		// The stored access token expired, end the session it belongs to instead
+		err = h.authService.RevokeSession(r.Context(), bffSession.UserID, bffSession.SessionID, services.LogoutReasonUser, clientInfo(r))
+		if errors.Is(err, services.ErrSessionNotFound) {
+			err = nil
+		}
+	}
+
+	switch {
+	case errors.Is(err, services.ErrInvalidAccessToken):
+		writeError(w, http.StatusUnauthorized, "Invalid token")
+		return
+	case err != nil:
+		log.Printf("Logout error: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to logout")
+		return
+	}
+
+	// Clear cookies
+	h.setLoginCookie(w, "", -1)
+
+	writeJSON(w, http.StatusOK, MessageResponse{Message: "Logged out"})
+}
+
 // Cancel login session
 func (h *AuthHandler) CancelLogin(w http.ResponseWriter, r *http.Request) {
-	sessionID, err := getCookie(r, "session_id")
+	sessionID, err := getCookie(r, config.LoginCookieName)
 	if err != nil {
 		writeError(w, http.StatusBadRequest, "No active session")
 		return
 	}
 
-	err = h.authService.CancelSession(sessionID)
+	err = h.authService.CancelSession(r.Context(), sessionID)
 	if err != nil {
 		log.Printf("Cancel session error: %v", err)
 		writeError(w, http.StatusInternalServerError, err.Error())
@@ -129,47 +389,32 @@ func (h *AuthHandler) CancelLogin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Clear cookie
-	setCookie(w, "session_id", "", -1)
+	h.setLoginCookie(w, "", -1)
 
 	writeJSON(w, http.StatusOK, MessageResponse{Message: "Session cancelled"})
 }
 
 // Check session status
 func (h *AuthHandler) SessionStatus(w http.ResponseWriter, r *http.Request) {
-	sessionID, err := getCookie(r, "session_id")
+	sessionID, err := getCookie(r, config.LoginCookieName)
 	if err != nil {
 		writeJSON(w, http.StatusOK, SessionStatusResponse{Valid: false})
 		return
 	}
 
-	valid := h.authService.ValidateSession(sessionID)
+	valid := h.authService.ValidateSession(r.Context(), sessionID)
 	writeJSON(w, http.StatusOK, SessionStatusResponse{
 		Valid:     valid,
 		SessionID: sessionID,
 	})
 }
 
-// Protected endpoint
+// Protected endpoint, the user was authenticated by AuthMiddleware from either
+// the bearer token or the BFF session cookie
 func (h *AuthHandler) Profile(w http.ResponseWriter, r *http.Request) {
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" || len(authHeader) < 8 {
-		writeError(w, http.StatusUnauthorized, "Missing or invalid authorization header")
-		return
-	}
-
-	// Extract token
-	if !strings.HasPrefix(authHeader, "Bearer ") {
-		writeError(w, http.StatusUnauthorized, "Invalid authorization header format")
-		return
-	}
-
-	token := authHeader[7:] // Remove "Bearer "
-
-	// Parse user
-	user, err := h.authService.ParseUser(token)
-	if err != nil {
-		log.Printf("Profile error: %v", err)
-		writeError(w, http.StatusUnauthorized, "Invalid token")
+	user, ok := custommiddleware.UserFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/SAP-2025/auth-service/internal/services"
+	"log"
+	"net/http"
+)
+
+type AuthzHandler struct {
+	authz   *services.AuthzService
+	clients *services.ClientRegistry
+}
+
+func NewAuthzHandler(authz *services.AuthzService, clients *services.ClientRegistry) *AuthzHandler {
+	return &AuthzHandler{authz: authz, clients: clients}
+}
+
+type AuthzBatchRequest struct {
+	Checks []services.AuthzCheck `json:"checks"`
+}
+
+type AuthzBatchResponse struct {
+	Decisions []services.PolicyDecision `json:"decisions"`
+}
+
+// authenticateClient checks the credentials of the calling service
+func (h *AuthzHandler) authenticateClient(w http.ResponseWriter, r *http.Request) (string, bool) {
+	client, ok := authenticateClient(h.clients, w, r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "Invalid client credentials")
+		return "", false
+	}
+	return client.ID, true
+}
+
+// Check decides whether a user may perform an action on a resource
+func (h *AuthzHandler) Check(w http.ResponseWriter, r *http.Request) {
+	clientID, ok := h.authenticateClient(w, r)
+	if !ok {
+		return
+	}
+
+	var check services.AuthzCheck
+	if err := json.NewDecoder(r.Body).Decode(&check); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	decision, err := h.authz.Check(r.Context(), &check, clientID, clientInfo(r))
+	if errors.Is(err, services.ErrInvalidCheck) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	} else if err != nil {
+		log.Printf("Authorization check error: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to evaluate authorization check")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, decision)
+}
+
+// CheckBatch decides several checks at once, decisions follow the order of the checks
+func (h *AuthzHandler) CheckBatch(w http.ResponseWriter, r *http.Request) {
+	clientID, ok := h.authenticateClient(w, r)
+	if !ok {
+		return
+	}
+
+	var req AuthzBatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	decisions, err := h.authz.CheckBatch(r.Context(), req.Checks, clientID, clientInfo(r))
+	if errors.Is(err, services.ErrInvalidCheck) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	} else if err != nil {
+		log.Printf("Authorization check error: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to evaluate authorization checks")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, AuthzBatchResponse{Decisions: decisions})
+}
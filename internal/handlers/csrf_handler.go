@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	custommiddleware "github.com/SAP-2025/auth-service/internal/middleware"
+	"net/http"
+)
+
+type CSRFHandler struct {
+	csrf *custommiddleware.CSRFProtector
+}
+
+func NewCSRFHandler(csrf *custommiddleware.CSRFProtector) *CSRFHandler {
+	return &CSRFHandler{csrf: csrf}
+}
+
+type CSRFTokenResponse struct {
+	Token  string `json:"csrf_token"`
+	Header string `json:"header"`
+}
+
+// Token returns the CSRF token of the current session, to be sent back in the
+// X-CSRF-Token header of state changing requests
+func (h *CSRFHandler) Token(w http.ResponseWriter, r *http.Request) {
+	token, ok := h.csrf.Token(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "No active session")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, CSRFTokenResponse{
+		Token:  token,
+		Header: custommiddleware.CSRFHeader,
+	})
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"github.com/SAP-2025/auth-service/internal/config"
+	"github.com/SAP-2025/auth-service/internal/services"
+	"log"
+	"net/http"
+)
+
+type OAuthHandler struct {
+	authService   *services.AuthService
+	clients       *services.ClientRegistry
+	introspection *services.IntrospectionService
+}
+
+func NewOAuthHandler(authService *services.AuthService, clients *services.ClientRegistry, introspection *services.IntrospectionService) *OAuthHandler {
+	return &OAuthHandler{
+		authService:   authService,
+		clients:       clients,
+		introspection: introspection,
+	}
+}
+
+// OAuthErrorResponse is the error format of RFC 6749 section 5.2
+type OAuthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+func (h *OAuthHandler) authenticateClient(w http.ResponseWriter, r *http.Request) (*config.OAuthClient, bool) {
+	client, ok := authenticateClient(h.clients, w, r)
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, OAuthErrorResponse{Error: "invalid_client"})
+		return nil, false
+	}
+	return client, true
+}
+
+// authenticateClient checks the credentials of an internal service, sent with
+// HTTP Basic auth or as client_id and client_secret in a form body. On failure
+// it only sets the challenge header, callers write the error in their format.
+func authenticateClient(clients *services.ClientRegistry, w http.ResponseWriter, r *http.Request) (*config.OAuthClient, bool) {
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID = r.PostFormValue("client_id")
+		clientSecret = r.PostFormValue("client_secret")
+	}
+
+	client, ok := clients.Authenticate(clientID, clientSecret)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Basic realm="auth-service"`)
+		return nil, false
+	}
+	return client, true
+}
+
+// Introspect implements RFC 7662 token introspection for internal services
+func (h *OAuthHandler) Introspect(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, OAuthErrorResponse{Error: "invalid_request"})
+		return
+	}
+
+	if _, ok := h.authenticateClient(w, r); !ok {
+		return
+	}
+
+	token := r.PostForm.Get("token")
+	if token == "" {
+		writeJSON(w, http.StatusBadRequest, OAuthErrorResponse{Error: "invalid_request", ErrorDescription: "missing token"})
+		return
+	}
+
+	resp, err := h.introspection.Introspect(r.Context(), token, r.PostForm.Get("token_type_hint"))
+	if err != nil {
+		log.Printf("Introspection error: %v", err)
+		writeJSON(w, http.StatusInternalServerError, OAuthErrorResponse{Error: "server_error"})
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Revoke implements RFC 7009 token revocation
+func (h *OAuthHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, OAuthErrorResponse{Error: "invalid_request"})
+		return
+	}
+
+	if _, ok := h.authenticateClient(w, r); !ok {
+		return
+	}
+
+	token := r.PostForm.Get("token")
+	if token == "" {
+		writeJSON(w, http.StatusBadRequest, OAuthErrorResponse{Error: "invalid_request", ErrorDescription: "missing token"})
+		return
+	}
+
+	err := h.authService.RevokeToken(r.Context(), token, r.PostForm.Get("token_type_hint"), clientInfo(r))
+	if err != nil {
+		log.Printf("Revocation error: %v", err)
+		writeJSON(w, http.StatusServiceUnavailable, OAuthErrorResponse{Error: "temporarily_unavailable"})
+		return
+	}
+
+	// Invalid tokens are answered with 200 as well, see RFC 7009 section 2.2
+	w.WriteHeader(http.StatusOK)
+}
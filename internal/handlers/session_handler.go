@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"errors"
+	custommiddleware "github.com/SAP-2025/auth-service/internal/middleware"
+	"github.com/SAP-2025/auth-service/internal/models"
+	"github.com/SAP-2025/auth-service/internal/services"
+	"github.com/SAP-2025/auth-service/internal/utils"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type SessionHandler struct {
+	authService *services.AuthService
+}
+
+func NewSessionHandler(authService *services.AuthService) *SessionHandler {
+	return &SessionHandler{authService: authService}
+}
+
+type SessionResponse struct {
+	ID         string              `json:"id"`
+	CreatedAt  time.Time           `json:"created_at"`
+	LastUsedAt time.Time           `json:"last_used_at"`
+	ExpiresAt  time.Time           `json:"expires_at"`
+	IPAddress  string              `json:"ip_address"`
+	UserAgent  string              `json:"user_agent"`
+	Device     utils.UserAgentInfo `json:"device"`
+	Current    bool                `json:"current"`
+}
+
+type SessionListResponse struct {
+	Sessions []SessionResponse `json:"sessions"`
+}
+
+type RevokeSessionsResponse struct {
+	Revoked int `json:"revoked"`
+}
+
+// currentUser resolves the authenticated user and the session of the request token
+func (h *SessionHandler) currentUser(w http.ResponseWriter, r *http.Request) (*models.User, string, bool) {
+	claims, ok := custommiddleware.UserFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return nil, "", false
+	}
+
+	user, err := h.authService.CurrentUser(r.Context(), claims)
+	if err != nil {
+		log.Printf("Session user error: %v", err)
+		writeError(w, http.StatusUnauthorized, "Unknown user")
+		return nil, "", false
+	}
+
+	return user, claims.SessionID, true
+}
+
+// List sessions of the current user
+func (h *SessionHandler) List(w http.ResponseWriter, r *http.Request) {
+	user, currentSessionID, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	sessions, err := h.authService.ListSessions(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("List sessions error: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to list sessions")
+		return
+	}
+
+	resp := SessionListResponse{Sessions: make([]SessionResponse, 0, len(sessions))}
+	for _, session := range sessions {
+		resp.Sessions = append(resp.Sessions, newSessionResponse(&session, currentSessionID))
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func newSessionResponse(session *models.UserSession, currentSessionID string) SessionResponse {
+	sessionID := session.ID.String()
+	return SessionResponse{
+		ID:         sessionID,
+		CreatedAt:  session.CreatedAt,
+		LastUsedAt: session.LastUsedAt,
+		ExpiresAt:  session.ExpiresAt,
+		IPAddress:  session.IPAddress.String(),
+		UserAgent:  session.UserAgent,
+		Device:     utils.ParseUserAgent(session.UserAgent),
+		Current:    sessionID == currentSessionID,
+	}
+}
+
+// Revoke a single session of the current user
+func (h *SessionHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	user, _, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	sessionID := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(sessionID); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid session id")
+		return
+	}
+
+	err := h.authService.RevokeSession(r.Context(), user.ID, sessionID, services.LogoutReasonUser, clientInfo(r))
+	if errors.Is(err, services.ErrSessionNotFound) {
+		writeError(w, http.StatusNotFound, "Session not found")
+		return
+	} else if err != nil {
+		log.Printf("Revoke session error: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to revoke session")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, MessageResponse{Message: "Session revoked"})
+}
+
+// Revoke all sessions of the current user except the one making the request
+func (h *SessionHandler) RevokeOthers(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("others") != "true" {
+		writeError(w, http.StatusBadRequest, "Only others=true is supported")
+		return
+	}
+
+	user, currentSessionID, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	if currentSessionID == "" {
+		writeError(w, http.StatusBadRequest, "Current session not found")
+		return
+	}
+
+	revoked, err := h.authService.RevokeOtherSessions(r.Context(), user.ID, currentSessionID, services.LogoutReasonUser, clientInfo(r))
+	if err != nil {
+		log.Printf("Revoke sessions error: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to revoke sessions")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, RevokeSessionsResponse{Revoked: revoked})
+}
@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/SAP-2025/auth-service/internal/db"
+	custommiddleware "github.com/SAP-2025/auth-service/internal/middleware"
+	"github.com/SAP-2025/auth-service/internal/models"
+	"github.com/SAP-2025/auth-service/internal/services"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+type UserAdminHandler struct {
+	users *services.UserAdminService
+}
+
+func NewUserAdminHandler(users *services.UserAdminService) *UserAdminHandler {
+	return &UserAdminHandler{users: users}
+}
+
+type AdminUserResponse struct {
+	ID            uint      `json:"id"`
+	CasdoorUserID string    `json:"casdoor_user_id"`
+	Username      string    `json:"username"`
+	Email         string    `json:"email"`
+	Name          string    `json:"name"`
+	AvatarURL     string    `json:"avatar_url,omitempty"`
+	Role          string    `json:"role"`
+	Organization  string    `json:"organization,omitempty"`
+	IsActive      bool      `json:"is_active"`
+	LastLoginAt   time.Time `json:"last_login_at"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type UserListResponse struct {
+	Users    []AdminUserResponse `json:"users"`
+	Total    int64               `json:"total"`
+	Page     int                 `json:"page"`
+	PageSize int                 `json:"page_size"`
+}
+
+type AuthLogResponse struct {
+	EventType    string    `json:"event_type"`
+	Success      bool      `json:"success"`
+	IPAddress    string    `json:"ip_address,omitempty"`
+	UserAgent    string    `json:"user_agent,omitempty"`
+	ErrorMessage string    `json:"error_message,omitempty"`
+	Details      string    `json:"details,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type UserDetailResponse struct {
+	User     AdminUserResponse `json:"user"`
+	Sessions []SessionResponse `json:"sessions"`
+	AuthLogs []AuthLogResponse `json:"auth_logs"`
+}
+
+type SetRoleRequest struct {
+	Role string `json:"role"`
+}
+
+type SetActiveRequest struct {
+	Active *bool `json:"active"`
+}
+
+func newAdminUserResponse(user *models.User) AdminUserResponse {
+	return AdminUserResponse{
+		ID:            user.ID,
+		CasdoorUserID: user.CasdoorUserID,
+		Username:      user.Username,
+		Email:         user.Email,
+		Name:          user.Name,
+		AvatarURL:     user.AvatarURL,
+		Role:          user.Role,
+		Organization:  user.Organization,
+		IsActive:      user.IsActive,
+		LastLoginAt:   user.LastLoginAt,
+		CreatedAt:     user.CreatedAt,
+	}
+}
+
+// List users, filtered by q (username, name or email), role, organization and
+// active, paginated with page and page_size
+func (h *UserAdminHandler) List(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	page, err := queryInt(query.Get("page"), 1)
+	if err != nil || page < 1 {
+		writeError(w, http.StatusBadRequest, "Invalid page")
+		return
+	}
+	pageSize, err := queryInt(query.Get("page_size"), defaultPageSize)
+	if err != nil || pageSize < 1 || pageSize > maxPageSize {
+		writeError(w, http.StatusBadRequest, "Invalid page_size")
+		return
+	}
+
+	filter := db.UserFilter{
+		Query:        query.Get("q"),
+		Role:         query.Get("role"),
+		Organization: query.Get("organization"),
+		Offset:       (page - 1) * pageSize,
+		Limit:        pageSize,
+	}
+	if active := query.Get("active"); active != "" {
+		value, err := strconv.ParseBool(active)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid active")
+			return
+		}
+		filter.Active = &value
+	}
+
+	users, total, err := h.users.ListUsers(r.Context(), filter)
+	if err != nil {
+		log.Printf("List users error: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to list users")
+		return
+	}
+
+	resp := UserListResponse{
+		Users:    make([]AdminUserResponse, 0, len(users)),
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}
+	for _, user := range users {
+		resp.Users = append(resp.Users, newAdminUserResponse(&user))
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Get a user with their active sessions and recent audit entries
+func (h *UserAdminHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	detail, err := h.users.GetUserDetail(r.Context(), userID)
+	if errors.Is(err, services.ErrUserNotFound) {
+		writeError(w, http.StatusNotFound, "User not found")
+		return
+	} else if err != nil {
+		log.Printf("Get user error: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to get user")
+		return
+	}
+
+	resp := UserDetailResponse{
+		User:     newAdminUserResponse(detail.User),
+		Sessions: make([]SessionResponse, 0, len(detail.Sessions)),
+		AuthLogs: make([]AuthLogResponse, 0, len(detail.AuthLogs)),
+	}
+	for _, session := range detail.Sessions {
+		resp.Sessions = append(resp.Sessions, newSessionResponse(&session, ""))
+	}
+	for _, entry := range detail.AuthLogs {
+		resp.AuthLogs = append(resp.AuthLogs, AuthLogResponse{
+			EventType:    entry.EventType,
+			Success:      entry.Success,
+			IPAddress:    entry.IPAddress.String(),
+			UserAgent:    entry.UserAgent,
+			ErrorMessage: entry.ErrorMessage,
+			Details:      entry.Details,
+			CreatedAt:    entry.CreatedAt,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// SetRole changes the role of a user in Casdoor and locally
+func (h *UserAdminHandler) SetRole(w http.ResponseWriter, r *http.Request) {
+	admin, userID, ok := h.adminAndTarget(w, r)
+	if !ok {
+		return
+	}
+
+	var req SetRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Role == "" {
+		writeError(w, http.StatusBadRequest, "Missing role")
+		return
+	}
+
+	user, err := h.users.SetRole(r.Context(), admin, userID, req.Role, clientInfo(r))
+	if err != nil {
+		h.writeChangeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newAdminUserResponse(user))
+}
+
+// SetActive activates or deactivates a user in Casdoor and locally
+func (h *UserAdminHandler) SetActive(w http.ResponseWriter, r *http.Request) {
+	admin, userID, ok := h.adminAndTarget(w, r)
+	if !ok {
+		return
+	}
+
+	var req SetActiveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Active == nil {
+		writeError(w, http.StatusBadRequest, "Missing active")
+		return
+	}
+
+	user, err := h.users.SetActive(r.Context(), admin, userID, *req.Active, clientInfo(r))
+	if err != nil {
+		h.writeChangeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newAdminUserResponse(user))
+}
+
+func (h *UserAdminHandler) adminAndTarget(w http.ResponseWriter, r *http.Request) (*models.User, uint, bool) {
+	admin, ok := custommiddleware.CurrentUserFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return nil, 0, false
+	}
+
+	userID, ok := userIDParam(w, r)
+	if !ok {
+		return nil, 0, false
+	}
+	return admin, userID, true
+}
+
+func (h *UserAdminHandler) writeChangeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		writeError(w, http.StatusNotFound, "User not found")
+	case errors.Is(err, services.ErrInvalidRole), errors.Is(err, services.ErrRoleNotMirrored):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrSelfModification), errors.Is(err, services.ErrRoleOverridden):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrCasdoorSync):
+		log.Printf("Casdoor sync error: %v", err)
+		writeError(w, http.StatusBadGateway, "Failed to update the user in Casdoor")
+	default:
+		log.Printf("Update user error: %v", err)
+		writeError(w, http.StatusInternalServerError, "Failed to update user")
+	}
+}
+
+func userIDParam(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id == 0 {
+		writeError(w, http.StatusBadRequest, "Invalid user id")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// queryInt parses an integer query parameter, returning fallback when it is absent
+func queryInt(value string, fallback int) (int, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(value)
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"github.com/SAP-2025/auth-service/internal/config"
+	"github.com/SAP-2025/auth-service/internal/services"
+	"net/http"
+	"strings"
+)
+
+type WellKnownHandler struct {
+	cfg        *config.Config
+	keyManager *services.KeyManager
+	endpoints  DiscoveryEndpoints
+}
+
+func NewWellKnownHandler(keyManager *services.KeyManager, cfg *config.Config) *WellKnownHandler {
+	return &WellKnownHandler{cfg: cfg, keyManager: keyManager}
+}
+
+// DiscoveryEndpoints holds the route paths advertised in the discovery document.
+// Empty paths are left out.
+type DiscoveryEndpoints struct {
+	JWKS          string
+	Introspection string
+	Revocation    string
+}
+
+// OpenIDConfiguration is the OpenID Connect discovery document. Login, refresh
+// and profile are not OAuth endpoints, so only the endpoints implemented here
+// are advertised.
+type OpenIDConfiguration struct {
+	Issuer                                    string   `json:"issuer"`
+	JWKSURI                                   string   `json:"jwks_uri"`
+	IntrospectionEndpoint                     string   `json:"introspection_endpoint,omitempty"`
+	RevocationEndpoint                        string   `json:"revocation_endpoint,omitempty"`
+	IntrospectionEndpointAuthMethodsSupported []string `json:"introspection_endpoint_auth_methods_supported,omitempty"`
+	RevocationEndpointAuthMethodsSupported    []string `json:"revocation_endpoint_auth_methods_supported,omitempty"`
+	ScopesSupported                           []string `json:"scopes_supported"`
+	ResponseTypesSupported                    []string `json:"response_types_supported"`
+	SubjectTypesSupported                     []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported          []string `json:"id_token_signing_alg_values_supported"`
+}
+
+// SetEndpoints sets the paths advertised by the discovery document, called
+// once the routes are registered
+func (h *WellKnownHandler) SetEndpoints(endpoints DiscoveryEndpoints) {
+	h.endpoints = endpoints
+}
+
+// JWKS publishes the public keys access tokens are verified with
+func (h *WellKnownHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	// Short cache so new keys published ahead of rotation reach verifiers in time
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	writeJSON(w, http.StatusOK, h.keyManager.JWKS())
+}
+
+// OpenIDConfiguration publishes the discovery document of this issuer
+func (h *WellKnownHandler) OpenIDConfiguration(w http.ResponseWriter, r *http.Request) {
+	base := strings.TrimSuffix(h.cfg.JWT.Issuer, "/")
+	url := func(path string) string {
+		if path == "" {
+			return ""
+		}
+		return base + path
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	writeJSON(w, http.StatusOK, OpenIDConfiguration{
+		Issuer:                h.cfg.JWT.Issuer,
+		JWKSURI:               url(h.endpoints.JWKS),
+		IntrospectionEndpoint: url(h.endpoints.Introspection),
+		RevocationEndpoint:    url(h.endpoints.Revocation),
+		IntrospectionEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post"},
+		RevocationEndpointAuthMethodsSupported:    []string{"client_secret_basic", "client_secret_post"},
+		ScopesSupported:                           h.cfg.TokenScopes(),
+		ResponseTypesSupported:                    []string{"code"},
+		SubjectTypesSupported:                     []string{"public"},
+		IDTokenSigningAlgValuesSupported:          h.keyManager.Algorithms(),
+	})
+}
@@ -2,7 +2,10 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"github.com/SAP-2025/auth-service/internal/services"
+	"log"
+	"net"
 	"net/http"
 	"strings"
 )
@@ -11,20 +14,32 @@ type contextKey string
 
 const UserContextKey contextKey = "user"
 
-// AuthMiddleware for protecting routes
-func AuthMiddleware(authService *services.AuthService) func(http.Handler) http.Handler {
+// AuthMiddleware for protecting routes. A bearer token takes precedence; in BFF
+// mode the session cookie is resolved to the tokens kept server side.
+func AuthMiddleware(authService *services.AuthService, bffSessions *services.BFFSessionStore) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var user *services.AccessClaims
+			var err error
+
 			authHeader := r.Header.Get("Authorization")
-			if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
+			if strings.HasPrefix(authHeader, "Bearer ") {
+				token := authHeader[7:] // Remove "Bearer "
+				user, err = authService.Authenticate(r.Context(), token)
+			} else if cookie, cookieErr := r.Cookie(bffSessions.CookieName()); bffSessions.Enabled() && cookieErr == nil {
+				user, err = bffSessions.Resolve(r.Context(), cookie.Value, ClientInfo(r))
+				if err != nil {
+					log.Printf("BFF session error: %v", err)
+				}
+			} else {
 				http.Error(w, "Unauthorized", http.StatusUnauthorized)
 				return
 			}
 
-			token := authHeader[7:] // Remove "Bearer "
-
-			user, err := authService.ParseUser(token)
-			if err != nil {
+			if errors.Is(err, services.ErrUserInactive) {
+				http.Error(w, "Account deactivated", http.StatusForbidden)
+				return
+			} else if err != nil {
 				http.Error(w, "Invalid token", http.StatusUnauthorized)
 				return
 			}
@@ -36,13 +51,37 @@ func AuthMiddleware(authService *services.AuthService) func(http.Handler) http.H
 	}
 }
 
-// CORS middleware
-func CORS() func(http.Handler) http.Handler {
+// ClientInfo returns the address and user agent of the client sending r
+func ClientInfo(r *http.Request) services.ClientInfo {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		ip = host
+	}
+	return services.ClientInfo{
+		IPAddress: ip,
+		UserAgent: r.UserAgent(),
+	}
+}
+
+// CORS middleware. Without an allowlist any origin is allowed, but browsers then
+// refuse to send cookies; allowed origins are echoed so cookies work for them.
+func CORS(allowedOrigins []string) func(http.Handler) http.Handler {
+	origins := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		origins[normalizeOrigin(origin)] = true
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+			origin := r.Header.Get("Origin")
+			if len(origins) == 0 {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else if origins[normalizeOrigin(origin)] {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Add("Vary", "Origin")
+			}
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, "+CSRFHeader)
 			w.Header().Set("Access-Control-Allow-Credentials", "true")
 
 			if r.Method == "OPTIONS" {
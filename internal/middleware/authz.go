@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/SAP-2025/auth-service/internal/models"
+	"github.com/SAP-2025/auth-service/internal/services"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+const localUserContextKey contextKey = "local_user"
+
+// ForbiddenResponse is the body of a request denied by an Authorizer
+type ForbiddenResponse struct {
+	Error               string   `json:"error"`
+	Message             string   `json:"message"`
+	Role                string   `json:"role"`
+	RequiredRoles       []string `json:"required_roles,omitempty"`
+	RequiredPermissions []string `json:"required_permissions,omitempty"`
+}
+
+// UserFromContext returns the claims of the user authenticated by AuthMiddleware
+func UserFromContext(ctx context.Context) (*services.AccessClaims, bool) {
+	claims, ok := ctx.Value(UserContextKey).(*services.AccessClaims)
+	return claims, ok
+}
+
+// CurrentUserFromContext returns the local user loaded by an Authorizer
+func CurrentUserFromContext(ctx context.Context) (*models.User, bool) {
+	user, ok := ctx.Value(localUserContextKey).(*models.User)
+	return user, ok
+}
+
+// Authorizer guards routes behind AuthMiddleware by role or permission. The
+// role is read from the database so a demoted user loses access at once.
+type Authorizer struct {
+	authService    *services.AuthService
+	authLogService *services.AuthLogService
+	permissions    *services.RolePermissions
+}
+
+func NewAuthorizer(authService *services.AuthService, authLogService *services.AuthLogService, permissions *services.RolePermissions) *Authorizer {
+	return &Authorizer{
+		authService:    authService,
+		authLogService: authLogService,
+		permissions:    permissions,
+	}
+}
+
+// RequireRole lets through users holding one of roles
+func (a *Authorizer) RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return a.require(func(user *models.User) bool {
+		return slices.Contains(roles, user.Role)
+	}, func(response *ForbiddenResponse) {
+		response.Message = "Requires role " + strings.Join(roles, " or ")
+		response.RequiredRoles = roles
+	})
+}
+
+// RequireAnyPermission lets through users whose role grants one of permissions
+func (a *Authorizer) RequireAnyPermission(permissions ...string) func(http.Handler) http.Handler {
+	return a.require(func(user *models.User) bool {
+		for _, permission := range permissions {
+			if a.permissions.Has(user.Role, permission) {
+				return true
+			}
+		}
+		return false
+	}, func(response *ForbiddenResponse) {
+		response.Message = "Requires permission " + strings.Join(permissions, " or ")
+		response.RequiredPermissions = permissions
+	})
+}
+
+func (a *Authorizer) require(allowed func(*models.User) bool, describe func(*ForbiddenResponse)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := UserFromContext(r.Context())
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			user, err := a.authService.CurrentUser(r.Context(), claims)
+			if err != nil {
+				log.Printf("Authorization user error: %v", err)
+				http.Error(w, "Unknown user", http.StatusUnauthorized)
+				return
+			}
+
+			if !allowed(user) {
+				response := ForbiddenResponse{Error: "forbidden", Role: user.Role}
+				describe(&response)
+
+				reason := fmt.Sprintf("%s %s: %s", r.Method, r.URL.Path, response.Message)
+				a.authLogService.Record(r.Context(), user.ID, "access_denied", ClientInfo(r), false, reason)
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusForbidden)
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), localUserContextKey, user)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
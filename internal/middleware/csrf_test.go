@@ -0,0 +1,182 @@
+package middleware
+
+import (
+	"github.com/SAP-2025/auth-service/internal/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCSRFProtectorMiddleware(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.JWT.Secret = "secret"
+	sessionCookie, loginCookie := cfg.BFFCookieName(), config.LoginCookieName
+
+	p := NewCSRFProtector(cfg.CSRFSecret(), []string{"https://app.example.com/"}, sessionCookie, loginCookie)
+	other := NewCSRFProtector("other-secret", nil, sessionCookie)
+	validToken := p.sign("session-1")
+
+	tests := []struct {
+		name    string
+		method  string
+		cookie  *http.Cookie
+		origin  string
+		referer string
+		token   string
+		want    int
+	}{
+		{
+			name:   "safe method skips checks",
+			method: http.MethodGet,
+			cookie: &http.Cookie{Name: sessionCookie, Value: "session-1"},
+			origin: "https://evil.example.com",
+			want:   http.StatusOK,
+		},
+		{
+			name:   "no session cookie skips checks",
+			method: http.MethodPost,
+			origin: "https://evil.example.com",
+			want:   http.StatusOK,
+		},
+		{
+			name:   "allowed origin with valid token",
+			method: http.MethodPost,
+			cookie: &http.Cookie{Name: sessionCookie, Value: "session-1"},
+			origin: "https://app.example.com",
+			token:  validToken,
+			want:   http.StatusOK,
+		},
+		{
+			name:   "token bound to any protected cookie",
+			method: http.MethodDelete,
+			cookie: &http.Cookie{Name: loginCookie, Value: "session-1"},
+			origin: "https://APP.example.com",
+			token:  validToken,
+			want:   http.StatusOK,
+		},
+		{
+			name:   "same host origin",
+			method: http.MethodPost,
+			cookie: &http.Cookie{Name: sessionCookie, Value: "session-1"},
+			origin: "http://auth.example.com",
+			token:  validToken,
+			want:   http.StatusOK,
+		},
+		{
+			name:   "no origin or referer relies on token",
+			method: http.MethodPost,
+			cookie: &http.Cookie{Name: sessionCookie, Value: "session-1"},
+			token:  validToken,
+			want:   http.StatusOK,
+		},
+		{
+			name:    "allowed referer",
+			method:  http.MethodPut,
+			cookie:  &http.Cookie{Name: sessionCookie, Value: "session-1"},
+			referer: "https://app.example.com/settings?tab=sessions",
+			token:   validToken,
+			want:    http.StatusOK,
+		},
+		{
+			name:   "foreign origin",
+			method: http.MethodPost,
+			cookie: &http.Cookie{Name: sessionCookie, Value: "session-1"},
+			origin: "https://evil.example.com",
+			token:  validToken,
+			want:   http.StatusForbidden,
+		},
+		{
+			name:   "null origin",
+			method: http.MethodPost,
+			cookie: &http.Cookie{Name: sessionCookie, Value: "session-1"},
+			origin: "null",
+			token:  validToken,
+			want:   http.StatusForbidden,
+		},
+		{
+			name:    "foreign referer",
+			method:  http.MethodPost,
+			cookie:  &http.Cookie{Name: sessionCookie, Value: "session-1"},
+			referer: "https://app.example.com.evil.com/",
+			token:   validToken,
+			want:    http.StatusForbidden,
+		},
+		{
+			name:    "origin checked before referer",
+			method:  http.MethodPost,
+			cookie:  &http.Cookie{Name: sessionCookie, Value: "session-1"},
+			origin:  "https://evil.example.com",
+			referer: "https://app.example.com/",
+			token:   validToken,
+			want:    http.StatusForbidden,
+		},
+		{
+			name:   "missing token",
+			method: http.MethodPost,
+			cookie: &http.Cookie{Name: sessionCookie, Value: "session-1"},
+			origin: "https://app.example.com",
+			want:   http.StatusForbidden,
+		},
+		{
+			name:   "token of another session",
+			method: http.MethodPost,
+			cookie: &http.Cookie{Name: sessionCookie, Value: "session-2"},
+			origin: "https://app.example.com",
+			token:  validToken,
+			want:   http.StatusForbidden,
+		},
+		{
+			name:   "token signed with another secret",
+			method: http.MethodPost,
+			cookie: &http.Cookie{Name: sessionCookie, Value: "session-1"},
+			origin: "https://app.example.com",
+			token:  other.sign("session-1"),
+			want:   http.StatusForbidden,
+		},
+	}
+
+	handler := p.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "http://auth.example.com/auth/logout", nil)
+			if tt.cookie != nil {
+				r.AddCookie(tt.cookie)
+			}
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if tt.referer != "" {
+				r.Header.Set("Referer", tt.referer)
+			}
+			if tt.token != "" {
+				r.Header.Set(CSRFHeader, tt.token)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSRFProtectorToken(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.JWT.Secret = "secret"
+	p := NewCSRFProtector(cfg.CSRFSecret(), nil, cfg.BFFCookieName(), config.LoginCookieName)
+
+	r := httptest.NewRequest(http.MethodGet, "/csrf-token", nil)
+	if _, ok := p.Token(r); ok {
+		t.Fatal("Token() without session cookie reported a token")
+	}
+
+	r.AddCookie(&http.Cookie{Name: cfg.BFFCookieName(), Value: "session-1"})
+	token, ok := p.Token(r)
+	if !ok || token != p.sign("session-1") {
+		t.Errorf("Token() = %q, %v, want the signature of the session cookie", token, ok)
+	}
+}
@@ -13,4 +13,5 @@ type AuthLog struct {
 	UserAgent    string
 	Success      bool `gorm:"default:true"`
 	ErrorMessage string
+	Details      string // target of admin actions
 }
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// PolicySet is a versioned authorization policy document in YAML or JSON.
+// The highest version is the active one.
+type PolicySet struct {
+	Version   int    `gorm:"primaryKey;autoIncrement:false"`
+	Document  string `gorm:"not null"`
+	Comment   string
+	CreatedAt time.Time
+}
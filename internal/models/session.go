@@ -9,10 +9,16 @@ import (
 type UserSession struct {
 	ID               uuid.UUID `gorm:"primaryKey;default:gen_random_uuid()"`
 	UserID           uint      `gorm:"references:users(id);onDelete:CASCADE"`
-	RefreshTokenHash string    `gorm:"not null"`
+	RefreshTokenHash string    `gorm:"not null;uniqueIndex"`
 	ExpiresAt        time.Time `gorm:"not null"`
 	CreatedAt        time.Time `gorm:"default:NOW()"`
 	LastUsedAt       time.Time `gorm:"default:NOW()"`
 	UserAgent        string
 	IPAddress        net.IP
+	// jti of the access token most recently issued for this session
+	AccessTokenID string
+	// Casdoor tokens backing this session, encrypted: the refresh token renews the
+	// upstream identity proof, the ID token ends the Casdoor session on logout
+	CasdoorIDToken      string
+	CasdoorRefreshToken string
 }
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// SigningKey is a key used to sign access tokens. A key signs tokens between
+// NotBefore and NotAfter and stays published for verification until ExpiresAt.
+type SigningKey struct {
+	ID         string    `gorm:"primaryKey"` // kid
+	Algorithm  string    `gorm:"not null"`
+	PrivateKey []byte    `gorm:"not null"` // PKCS#8 PEM, encrypted when stored in the database
+	PublicKey  string    `gorm:"not null"` // PKIX PEM
+	NotBefore  time.Time `gorm:"not null"`
+	NotAfter   time.Time `gorm:"not null"`
+	ExpiresAt  time.Time `gorm:"not null;index"`
+	CreatedAt  time.Time
+}
@@ -2,6 +2,7 @@
 package routes
 
 import (
+	"github.com/SAP-2025/auth-service/internal/config"
 	"github.com/SAP-2025/auth-service/internal/handlers"
 	custommiddleware "github.com/SAP-2025/auth-service/internal/middleware"
 	"github.com/SAP-2025/auth-service/internal/services"
@@ -10,7 +11,7 @@ import (
 	"net/http"
 )
 
-func SetupRoutes(authService *services.AuthService) *chi.Mux {
+func SetupRoutes(authService *services.AuthService, keyManager *services.KeyManager, introspection *services.IntrospectionService, bffSessions *services.BFFSessionStore, csrf *custommiddleware.CSRFProtector, authz *custommiddleware.Authorizer, epochs *services.RevocationEpochService, authzService *services.AuthzService, userAdmin *services.UserAdminService, clients *services.ClientRegistry, cfg *config.Config) *chi.Mux {
 	r := chi.NewRouter()
 
 	// Built-in middleware
@@ -21,10 +22,18 @@ func SetupRoutes(authService *services.AuthService) *chi.Mux {
 	r.Use(middleware.Timeout(60))
 
 	// Custom middleware
-	r.Use(custommiddleware.CORS())
+	r.Use(custommiddleware.CORS(cfg.Security.AllowedOrigins))
+	r.Use(csrf.Middleware)
 
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(authService)
+	authHandler := handlers.NewAuthHandler(authService, bffSessions, cfg)
+	sessionHandler := handlers.NewSessionHandler(authService)
+	wellKnownHandler := handlers.NewWellKnownHandler(keyManager, cfg)
+	oauthHandler := handlers.NewOAuthHandler(authService, clients, introspection)
+	csrfHandler := handlers.NewCSRFHandler(csrf)
+	adminHandler := handlers.NewAdminHandler(authService, epochs)
+	authzHandler := handlers.NewAuthzHandler(authzService, clients)
+	userAdminHandler := handlers.NewUserAdminHandler(userAdmin)
 
 	// Health check
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -33,19 +42,84 @@ func SetupRoutes(authService *services.AuthService) *chi.Mux {
 		w.Write([]byte(`{"status":"ok","redis":"connected"}`))
 	})
 
+	// Discovery
+	r.Get("/.well-known/jwks.json", wellKnownHandler.JWKS)
+	r.Get("/.well-known/openid-configuration", wellKnownHandler.OpenIDConfiguration)
+
 	// Auth routes (public)
 	r.Route("/auth", func(r chi.Router) {
 		r.Get("/login", authHandler.Login)
 		r.Get("/callback", authHandler.Callback)
 		r.Delete("/cancel", authHandler.CancelLogin)
 		r.Get("/session", authHandler.SessionStatus)
+		r.Post("/refresh", authHandler.Refresh)
+		r.Get("/csrf", csrfHandler.Token)
 
 		// Protected auth routes
 		r.Group(func(r chi.Router) {
-			r.Use(custommiddleware.AuthMiddleware(authService))
+			r.Use(custommiddleware.AuthMiddleware(authService, bffSessions))
 			r.Get("/profile", authHandler.Profile)
+			r.Post("/logout", authHandler.Logout)
+			r.Get("/sessions", sessionHandler.List)
+			r.Delete("/sessions", sessionHandler.RevokeOthers)
+			r.Delete("/sessions/{id}", sessionHandler.Revoke)
 		})
 	})
 
+	// Admin routes
+	r.Route("/admin", func(r chi.Router) {
+		r.Use(custommiddleware.AuthMiddleware(authService, bffSessions))
+		r.With(authz.RequireAnyPermission(services.PermissionTokensRevokeAll)).
+			Post("/revocation-epoch", adminHandler.BumpRevocationEpoch)
+
+		r.Group(func(r chi.Router) {
+			r.Use(authz.RequireAnyPermission(services.PermissionUsersRead))
+			r.Get("/users", userAdminHandler.List)
+			r.Get("/users/{id}", userAdminHandler.Get)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(authz.RequireAnyPermission(services.PermissionUsersWrite))
+			r.Put("/users/{id}/role", userAdminHandler.SetRole)
+			r.Put("/users/{id}/active", userAdminHandler.SetActive)
+		})
+	})
+
+	// OAuth endpoints for internal services (client credentials)
+	r.Route("/oauth", func(r chi.Router) {
+		r.Post("/introspect", oauthHandler.Introspect)
+		r.Post("/revoke", oauthHandler.Revoke)
+	})
+
+	// Policy decisions for internal services (client credentials)
+	r.Route("/authz", func(r chi.Router) {
+		r.Post("/check", authzHandler.Check)
+		r.Post("/check/batch", authzHandler.CheckBatch)
+	})
+
+	wellKnownHandler.SetEndpoints(discoveryEndpoints(r))
+
 	return r
 }
+
+// discoveryEndpoints maps the OpenID configuration endpoints to routes.
+// Only routes actually registered on the router are advertised.
+func discoveryEndpoints(r chi.Routes) handlers.DiscoveryEndpoints {
+	registered := make(map[string]bool)
+	chi.Walk(r, func(method, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		registered[method+" "+route] = true
+		return nil
+	})
+
+	path := func(method, route string) string {
+		if registered[method+" "+route] {
+			return route
+		}
+		return ""
+	}
+
+	return handlers.DiscoveryEndpoints{
+		JWKS:          path(http.MethodGet, "/.well-known/jwks.json"),
+		Introspection: path(http.MethodPost, "/oauth/introspect"),
+		Revocation:    path(http.MethodPost, "/oauth/revoke"),
+	}
+}
@@ -2,11 +2,17 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/SAP-2025/auth-service/internal/config"
+	"github.com/SAP-2025/auth-service/internal/models"
 	"github.com/SAP-2025/auth-service/internal/utils"
 	"github.com/google/uuid"
+	"log"
 	"net/http"
+	"net/url"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/casdoor/casdoor-go-sdk/casdoorsdk"
@@ -14,20 +20,28 @@ import (
 )
 
 type AuthService struct {
-	cfg           *config.Config
-	pkceStore     *PKCEStore
-	casdoorClient *casdoorsdk.Client
-	oauth2Config  *oauth2.Config
+	cfg            *config.Config
+	loginStates    LoginStateStore
+	sessionService *SessionService
+	userService    *UserService
+	eventService   *EventService
+	authLogService *AuthLogService
+	revokedTokens  *TokenRevocationStore
+	tokenService   *TokenService
+	casdoorClient  *casdoorsdk.Client
+	casdoorCerts   *CasdoorCertManager
+	idTokens       *IDTokenVerifier
+	oauth2Config   *oauth2.Config
 }
 
-func NewAuthService(pkceStore *PKCEStore, cfg *config.Config) *AuthService {
+func NewAuthService(loginStates LoginStateStore, sessionService *SessionService, userService *UserService, eventService *EventService, authLogService *AuthLogService, revokedTokens *TokenRevocationStore, tokenService *TokenService, casdoorCerts *CasdoorCertManager, cfg *config.Config) *AuthService {
 	client := config.NewCasdoorClient(cfg)
 
 	oauth2Config := &oauth2.Config{
 		ClientID:     cfg.OAuth2.Casdoor.ClientID,
 		ClientSecret: cfg.OAuth2.Casdoor.ClientSecret,
 		RedirectURL:  cfg.OAuth2.Casdoor.RedirectURI,
-		Scopes:       []string{"read"},
+		Scopes:       []string{"openid", "read"},
 		Endpoint: oauth2.Endpoint{
 			AuthURL:  cfg.OAuth2.Casdoor.BaseURL + "/api/login/oauth/authorize",
 			TokenURL: cfg.OAuth2.Casdoor.BaseURL + "/api/login/oauth/access_token",
@@ -35,29 +49,64 @@ func NewAuthService(pkceStore *PKCEStore, cfg *config.Config) *AuthService {
 	}
 
 	return &AuthService{
-		casdoorClient: client,
-		oauth2Config:  oauth2Config,
-		pkceStore:     pkceStore,
+		cfg:            cfg,
+		casdoorClient:  client,
+		casdoorCerts:   casdoorCerts,
+		idTokens:       NewIDTokenVerifier(casdoorCerts, cfg),
+		oauth2Config:   oauth2Config,
+		loginStates:    loginStates,
+		sessionService: sessionService,
+		userService:    userService,
+		eventService:   eventService,
+		authLogService: authLogService,
+		revokedTokens:  revokedTokens,
+		tokenService:   tokenService,
 	}
 }
 
+var (
+	ErrTokenRevoked        = errors.New("token has been revoked")
+	ErrInvalidReturnTo     = errors.New("return_to is not an allowed frontend URL")
+	ErrReturnToUnsupported = errors.New("return_to requires BFF mode, tokens cannot be passed in a redirect")
+)
+
 type LoginResponse struct {
 	LoginURL  string `json:"login_url"`
 	SessionID string `json:"session_id"`
 }
 
 type CallbackResponse struct {
-	AccessToken  string             `json:"access_token"`
-	RefreshToken string             `json:"refresh_token"`
-	ExpiresIn    int64              `json:"expires_in"`
-	User         *casdoorsdk.Claims `json:"user"`
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresIn    int64     `json:"expires_in"`
+	SessionID    string    `json:"session_id"`
+	User         *UserInfo `json:"user"`
 }
 
-func (s *AuthService) GetLoginURL() (*LoginResponse, error) {
+// GetLoginURL starts a login. returnTo is the optional frontend URL the
+// callback redirects to.
+func (s *AuthService) GetLoginURL(ctx context.Context, returnTo string) (*LoginResponse, error) {
+	if returnTo != "" {
+		if err := s.validateReturnTo(returnTo); err != nil {
+			return nil, err
+		}
+	}
+
 	sessionID := uuid.New().String()
 	pkceChallenge := utils.NewPKCEChallenge()
 
-	err := s.pkceStore.SavePKCE(sessionID, pkceChallenge)
+	// Binds the ID token to this login, see OpenID Connect Core 3.1.2.1
+	nonce, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.loginStates.Save(ctx, sessionID, &LoginState{
+		PKCEChallenge: *pkceChallenge,
+		Nonce:         nonce,
+		ReturnTo:      returnTo,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to save PKCE: %w", err)
 	}
@@ -65,6 +114,7 @@ func (s *AuthService) GetLoginURL() (*LoginResponse, error) {
 	authURL := s.oauth2Config.AuthCodeURL(sessionID,
 		oauth2.SetAuthURLParam("code_challenge", pkceChallenge.CodeChallenge),
 		oauth2.SetAuthURLParam("code_challenge_method", pkceChallenge.Method),
+		oauth2.SetAuthURLParam("nonce", nonce),
 	)
 
 	return &LoginResponse{
@@ -73,44 +123,505 @@ func (s *AuthService) GetLoginURL() (*LoginResponse, error) {
 	}, nil
 }
 
-func (s *AuthService) ExchangeCode(code, state string) (*CallbackResponse, error) {
-	pkceChallenge, err := s.pkceStore.GetAndDeletePKCE(state)
+// validateReturnTo only accepts absolute URLs on an allowed frontend origin,
+// anything else would make the callback an open redirect
+func (s *AuthService) validateReturnTo(returnTo string) error {
+	if !s.cfg.Security.BFF.Enabled {
+		return ErrReturnToUnsupported
+	}
+
+	target, err := url.Parse(returnTo)
+	if err != nil || target.Host == "" || (target.Scheme != "https" && target.Scheme != "http") {
+		return ErrInvalidReturnTo
+	}
+
+	origin := target.Scheme + "://" + target.Host
+	for _, allowed := range s.cfg.Security.AllowedOrigins {
+		if strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return nil
+		}
+	}
+	return ErrInvalidReturnTo
+}
+
+// ConsumeLoginState returns the state of a pending login, which can be used only once
+func (s *AuthService) ConsumeLoginState(ctx context.Context, state string) (*LoginState, error) {
+	loginState, err := s.loginStates.Consume(ctx, state)
 	if err != nil {
 		return nil, fmt.Errorf("invalid or expired session: %w", err)
 	}
+	return loginState, nil
+}
 
-	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{
-		Timeout: 10 * time.Second,
-	})
-
-	token, err := s.oauth2Config.Exchange(ctx, code,
-		oauth2.SetAuthURLParam("code_verifier", pkceChallenge.CodeVerifier),
+func (s *AuthService) ExchangeCode(ctx context.Context, code string, loginState *LoginState, client ClientInfo) (*CallbackResponse, error) {
+	token, err := s.oauth2Config.Exchange(s.oauth2Context(ctx), code,
+		oauth2.SetAuthURLParam("code_verifier", loginState.CodeVerifier),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("token exchange failed: %w", err)
 	}
 
-	user, err := s.casdoorClient.ParseJwtToken(token.AccessToken)
+	rawIDToken, _ := token.Extra("id_token").(string)
+	idToken, err := s.idTokens.Verify(rawIDToken, loginState.Nonce)
+	if err != nil {
+		return nil, s.rejectIDToken(ctx, err, client)
+	}
+
+	// The Casdoor token only proves the identity, clients get tokens minted by us
+	casdoorUser, err := s.casdoorCerts.ParseJwtToken(token.AccessToken)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse JWT: %w", err)
 	}
 
+	if casdoorUser.Subject != idToken.Subject {
+		err := &IDTokenError{Code: IDTokenSubjectMismatch}
+		return nil, s.rejectIDToken(ctx, err, client)
+	}
+
+	user, created, err := s.userService.UpsertUser(ctx, casdoorUser)
+	if errors.Is(err, ErrUserConflict) || errors.Is(err, ErrRoleUnmapped) {
+		s.authLogService.Record(ctx, 0, "login", client, false, err.Error())
+		return nil, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	if created {
+		if err := s.eventService.PublishUserCreatedEvent(user); err != nil {
+			log.Printf("Failed to publish user created event: %v", err)
+		}
+	}
+
+	if !user.IsActive {
+		s.authLogService.Record(ctx, user.ID, "login", client, false, ErrUserInactive.Error())
+		return nil, ErrUserInactive
+	}
+
+	// Held until the new session is stored, so it counts for concurrent logins
+	unlock, err := s.sessionService.LockUser(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if err := s.enforceSessionLimit(ctx, user, client); err != nil {
+		return nil, err
+	}
+
+	sessionID := uuid.New()
+	accessToken, claims, err := s.tokenService.IssueAccessToken(user, sessionID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	_, refreshToken, err := s.sessionService.CreateSession(ctx, sessionID, user.ID, claims.ID, token, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.newTokenResponse(accessToken, refreshToken, sessionID.String(), user), nil
+}
+
+// rejectIDToken records a failed ID token check; the user is not known yet
+func (s *AuthService) rejectIDToken(ctx context.Context, err error, client ClientInfo) error {
+	code := err.Error()
+	var idTokenErr *IDTokenError
+	if errors.As(err, &idTokenErr) {
+		code = idTokenErr.Code
+	}
+
+	log.Printf("Rejected ID token: %v", err)
+	s.authLogService.Record(ctx, 0, "login", client, false, code)
+	return err
+}
+
+func (s *AuthService) newTokenResponse(accessToken, refreshToken, sessionID string, user *models.User) *CallbackResponse {
 	return &CallbackResponse{
-		AccessToken:  token.AccessToken,
-		RefreshToken: token.RefreshToken,
-		ExpiresIn:    token.Expiry.Unix(),
-		User:         user,
-	}, nil
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.tokenService.TTL().Seconds()),
+		SessionID:    sessionID,
+		User:         NewUserInfo(user),
+	}
+}
+
+// enforceSessionLimit makes room for a new session of user according to the
+// concurrent session limit of their role, or rejects the login
+func (s *AuthService) enforceSessionLimit(ctx context.Context, user *models.User, client ClientInfo) error {
+	limit := s.cfg.SessionLimitFor(user.Role)
+	if limit.MaxConcurrentSessions <= 0 {
+		return nil
+	}
+
+	sessions, err := s.sessionService.ListSessions(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	excess := len(sessions) - limit.MaxConcurrentSessions + 1
+	if excess <= 0 {
+		return nil
+	}
+
+	switch limit.EvictionPolicy {
+	case config.EvictionPolicyReject:
+		s.authLogService.Record(ctx, user.ID, "login", client, false, ErrSessionLimitReached.Error())
+		return ErrSessionLimitReached
+	case config.EvictionPolicyEvictLRU:
+		sort.Slice(sessions, func(i, j int) bool {
+			return sessions[i].LastUsedAt.Before(sessions[j].LastUsedAt)
+		})
+	case config.EvictionPolicyEvictOldest:
+		sort.Slice(sessions, func(i, j int) bool {
+			return sessions[i].CreatedAt.Before(sessions[j].CreatedAt)
+		})
+	default:
+		return fmt.Errorf("unknown session eviction policy %q", limit.EvictionPolicy)
+	}
+
+	for _, session := range sessions[:excess] {
+		err := s.RevokeSession(ctx, user.ID, session.ID.String(), LogoutReasonSessionLimit, client)
+		if err != nil && !errors.Is(err, ErrSessionNotFound) {
+			return err
+		}
+	}
+
+	return nil
 }
 
-func (s *AuthService) ParseUser(accessToken string) (*casdoorsdk.Claims, error) {
-	return s.casdoorClient.ParseJwtToken(accessToken)
+// RefreshToken renews the access token of the session owning refreshToken and
+// rotates the refresh token, so every refresh token can be used only once
+func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string, client ClientInfo) (*CallbackResponse, error) {
+	session, err := s.sessionService.GetByRefreshToken(ctx, refreshToken)
+	if errors.Is(err, ErrInvalidRefreshToken) {
+		if rotated, ok := s.sessionService.FindRotatedToken(ctx, refreshToken); ok {
+			s.handleTokenReuse(ctx, rotated, client)
+			return nil, ErrRefreshTokenReused
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userService.GetByID(ctx, session.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.IsActive {
+		err := s.RevokeSession(ctx, user.ID, session.ID.String(), LogoutReasonDeactivated, client)
+		if err != nil && !errors.Is(err, ErrSessionNotFound) {
+			log.Printf("Failed to revoke session %s: %v", session.ID, err)
+		}
+		return nil, ErrUserInactive
+	}
+
+	// The refresh token was issued when the session was last used
+	revoked, err := s.revokedTokens.IssuedBeforeEpoch(ctx, user.Organization, session.LastUsedAt)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		err := s.RevokeSession(ctx, user.ID, session.ID.String(), LogoutReasonRevoked, client)
+		if err != nil && !errors.Is(err, ErrSessionNotFound) {
+			log.Printf("Failed to revoke session %s: %v", session.ID, err)
+		}
+		return nil, ErrTokenRevoked
+	}
+
+	casdoorRefreshToken, err := s.sessionService.OpenCasdoorToken(session.CasdoorRefreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// Renewing the Casdoor token proves the upstream identity is still valid
+	token, err := s.oauth2Config.TokenSource(s.oauth2Context(ctx), &oauth2.Token{
+		RefreshToken: casdoorRefreshToken,
+	}).Token()
+	if err != nil {
+		return nil, fmt.Errorf("upstream token refresh failed: %w", err)
+	}
+
+	casdoorUser, err := s.casdoorCerts.ParseJwtToken(token.AccessToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWT: %w", err)
+	}
+
+	// Roles follow Casdoor on every refresh, not only at login
+	synced, err := s.userService.SyncRole(ctx, user, casdoorUser)
+	if errors.Is(err, ErrRoleUnmapped) {
+		s.authLogService.Record(ctx, user.ID, "token_refresh", client, false, err.Error())
+		err := s.RevokeSession(ctx, user.ID, session.ID.String(), LogoutReasonRoleUnmapped, client)
+		if err != nil && !errors.Is(err, ErrSessionNotFound) {
+			log.Printf("Failed to revoke session %s: %v", session.ID, err)
+		}
+		return nil, ErrRoleUnmapped
+	} else if err != nil {
+		return nil, err
+	}
+	user = synced
+
+	sessionID := session.ID.String()
+	accessToken, claims, err := s.tokenService.IssueAccessToken(user, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	newRefreshToken, err := s.sessionService.RotateRefreshToken(ctx, session, refreshToken, claims.ID, token, client)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.eventService.PublishTokenRefreshedEvent(session.UserID, sessionID, client.IPAddress, client.UserAgent); err != nil {
+		log.Printf("Failed to publish token refreshed event: %v", err)
+	}
+
+	return s.newTokenResponse(accessToken, newRefreshToken, sessionID, user), nil
 }
 
-func (s *AuthService) ValidateSession(sessionID string) bool {
-	return s.pkceStore.ExistsPKCE(sessionID)
+// handleTokenReuse revokes the token family of a replayed refresh token, since
+// presenting an already rotated token means it was copied by someone else
+func (s *AuthService) handleTokenReuse(ctx context.Context, rotated *RotatedToken, client ClientInfo) {
+	var revoked int64 = 1
+	err := s.RevokeSession(ctx, rotated.UserID, rotated.SessionID, LogoutReasonReuse, client)
+	if errors.Is(err, ErrSessionNotFound) {
+		revoked = 0
+	} else if err != nil {
+		revoked = 0
+		log.Printf("Failed to revoke token family of session %s: %v", rotated.SessionID, err)
+	}
+
+	s.authLogService.Record(ctx, rotated.UserID, "token_reuse", client, false, ErrRefreshTokenReused.Error())
+
+	if err := s.eventService.PublishTokenReuseDetectedEvent(rotated.UserID, rotated.SessionID, revoked, client.IPAddress, client.UserAgent); err != nil {
+		log.Printf("Failed to publish token reuse event: %v", err)
+	}
+}
+
+// Logout ends the session the access token belongs to: the session row is deleted,
+// the access token is revoked and the Casdoor session is terminated
+func (s *AuthService) Logout(ctx context.Context, accessToken, reason string, client ClientInfo) error {
+	claims, err := s.ParseUser(accessToken)
+	if err != nil {
+		return err
+	}
+
+	userID, err := claims.UserID()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAccessToken, err)
+	}
+
+	// A session already ended by another logout or by expiry is not an error
+	session, err := s.sessionService.GetSession(ctx, userID, claims.SessionID)
+	if err != nil && !errors.Is(err, ErrSessionNotFound) {
+		return err
+	}
+	if session != nil {
+		if _, err := s.sessionService.DeleteSession(ctx, userID, claims.SessionID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.revokeAccessToken(ctx, userID, claims.SessionID, claims.ID, claims.ExpiresAt.Time); err != nil {
+		return err
+	}
+
+	if session != nil {
+		s.endCasdoorSession(userID, session)
+	}
+
+	s.authLogService.Record(ctx, userID, "logout", client, true, "")
+
+	if err := s.eventService.PublishLogoutEvent(userID, claims.SessionID, reason, client.IPAddress, client.UserAgent); err != nil {
+		log.Printf("Failed to publish logout event: %v", err)
+	}
+
+	return nil
+}
+
+// endCasdoorSession logs the user out of Casdoor; failures are only logged since
+// the local session is already gone
+func (s *AuthService) endCasdoorSession(userID uint, session *models.UserSession) {
+	if session.CasdoorIDToken == "" {
+		return
+	}
+	idToken, err := s.sessionService.OpenCasdoorToken(session.CasdoorIDToken)
+	if err != nil {
+		log.Printf("Failed to end Casdoor session for user %d: %v", userID, err)
+		return
+	}
+
+	_, err = s.casdoorClient.DoPost("logout", map[string]string{"id_token_hint": idToken}, nil, false, false)
+	if err != nil {
+		log.Printf("Failed to end Casdoor session for user %d: %v", userID, err)
+	}
+}
+
+// oauth2Context returns ctx carrying the HTTP client used for Casdoor token requests
+func (s *AuthService) oauth2Context(ctx context.Context) context.Context {
+	return context.WithValue(ctx, oauth2.HTTPClient, &http.Client{
+		Timeout: 10 * time.Second,
+	})
+}
+
+func (s *AuthService) ParseUser(accessToken string) (*AccessClaims, error) {
+	return s.tokenService.ParseAccessToken(accessToken)
+}
+
+// Authenticate parses the access token and rejects it if it has been revoked
+// or its user was deactivated
+func (s *AuthService) Authenticate(ctx context.Context, accessToken string) (*AccessClaims, error) {
+	claims, err := s.ParseUser(accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := s.revokedTokens.IsRevoked(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+
+	revoked, err = s.revokedTokens.IssuedBeforeEpoch(ctx, claims.Organization, claims.IssuedAt.Time)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+
+	userID, err := claims.UserID()
+	if err != nil {
+		return nil, err
+	}
+	active, err := s.userService.IsActive(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		return nil, ErrUserInactive
+	}
+
+	return claims, nil
+}
+
+// CurrentUser returns the local user the access token was issued to
+func (s *AuthService) CurrentUser(ctx context.Context, claims *AccessClaims) (*models.User, error) {
+	userID, err := claims.UserID()
+	if err != nil {
+		return nil, err
+	}
+	return s.userService.GetByID(ctx, userID)
+}
+
+// ListSessions returns the active sessions of a user
+func (s *AuthService) ListSessions(ctx context.Context, userID uint) ([]models.UserSession, error) {
+	return s.sessionService.ListSessions(ctx, userID)
+}
+
+// RevokeSession deletes one session of a user and revokes the access token
+// last issued for it, so the device is signed out immediately
+func (s *AuthService) RevokeSession(ctx context.Context, userID uint, sessionID, reason string, client ClientInfo) error {
+	session, err := s.sessionService.GetSession(ctx, userID, sessionID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.sessionService.DeleteSession(ctx, userID, sessionID); err != nil {
+		return err
+	}
+
+	// The access token was issued when the session was last used
+	if session.AccessTokenID != "" {
+		expiresAt := session.LastUsedAt.Add(s.tokenService.TTL())
+		if err := s.revokeAccessToken(ctx, userID, sessionID, session.AccessTokenID, expiresAt); err != nil {
+			return err
+		}
+	}
+
+	s.authLogService.Record(ctx, userID, "session_revoked", client, true, "")
+
+	if err := s.eventService.PublishLogoutEvent(userID, sessionID, reason, client.IPAddress, client.UserAgent); err != nil {
+		log.Printf("Failed to publish logout event: %v", err)
+	}
+
+	return nil
+}
+
+// RevokeToken implements RFC 7009 revocation: an access token is added to the
+// denylist, a refresh token ends its whole session. Unknown tokens are ignored.
+func (s *AuthService) RevokeToken(ctx context.Context, token, tokenTypeHint string, client ClientInfo) error {
+	if tokenTypeHint != "refresh_token" {
+		if claims, err := s.tokenService.ParseAccessToken(token); err == nil {
+			userID, err := claims.UserID()
+			if err != nil {
+				return nil
+			}
+			return s.revokeAccessToken(ctx, userID, claims.SessionID, claims.ID, claims.ExpiresAt.Time)
+		}
+	}
+
+	session, err := s.sessionService.GetByRefreshToken(ctx, token)
+	if errors.Is(err, ErrInvalidRefreshToken) || errors.Is(err, ErrRefreshTokenExpired) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	err = s.RevokeSession(ctx, session.UserID, session.ID.String(), LogoutReasonRevoked, client)
+	if errors.Is(err, ErrSessionNotFound) {
+		return nil
+	}
+	return err
+}
+
+// revokeAccessToken denylists the jti for the rest of the token lifetime and
+// tells other services to drop anything they cached for it
+func (s *AuthService) revokeAccessToken(ctx context.Context, userID uint, sessionID, jti string, expiresAt time.Time) error {
+	if err := s.revokedTokens.Revoke(ctx, jti, expiresAt); err != nil {
+		return err
+	}
+
+	if err := s.eventService.PublishTokenRevokedEvent(userID, sessionID, jti, expiresAt); err != nil {
+		log.Printf("Failed to publish token revoked event: %v", err)
+	}
+	return nil
+}
+
+// RevokeOtherSessions revokes every session of a user except keepSessionID,
+// all of them when keepSessionID is empty
+func (s *AuthService) RevokeOtherSessions(ctx context.Context, userID uint, keepSessionID, reason string, client ClientInfo) (int, error) {
+	sessions, err := s.sessionService.ListSessions(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	revoked := 0
+	for _, session := range sessions {
+		sessionID := session.ID.String()
+		if sessionID == keepSessionID {
+			continue
+		}
+		if err := s.RevokeSession(ctx, userID, sessionID, reason, client); err != nil && !errors.Is(err, ErrSessionNotFound) {
+			return revoked, err
+		}
+		revoked++
+	}
+
+	return revoked, nil
+}
+
+func (s *AuthService) ValidateSession(ctx context.Context, sessionID string) bool {
+	exists, err := s.loginStates.Exists(ctx, sessionID)
+	if err != nil {
+		log.Printf("Failed to check login state: %v", err)
+	}
+	return exists
 }
 
-func (s *AuthService) CancelSession(sessionID string) error {
-	return s.pkceStore.DeletePKCE(sessionID)
+func (s *AuthService) CancelSession(ctx context.Context, sessionID string) error {
+	return s.loginStates.Delete(ctx, sessionID)
 }
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"github.com/SAP-2025/auth-service/internal/db"
+	"github.com/SAP-2025/auth-service/internal/models"
+	"log"
+	"net"
+)
+
+type AuthLogService struct {
+	logs db.AuthLogRepository
+}
+
+func NewAuthLogService(logs db.AuthLogRepository) *AuthLogService {
+	return &AuthLogService{logs: logs}
+}
+
+// Record writes an audit entry; failures are logged and never block the caller
+func (s *AuthLogService) Record(ctx context.Context, userID uint, eventType string, client ClientInfo, success bool, errorMessage string) {
+	entry := &models.AuthLog{
+		UserID:       userID,
+		EventType:    eventType,
+		IPAddress:    net.ParseIP(client.IPAddress),
+		UserAgent:    client.UserAgent,
+		Success:      success,
+		ErrorMessage: errorMessage,
+	}
+
+	if err := s.logs.Create(ctx, entry); err != nil {
+		log.Printf("Failed to write auth log %q for user %d: %v", eventType, userID, err)
+	}
+}
+
+// RecordAdminAction writes an audit entry for an administrative action taken by actorID
+func (s *AuthLogService) RecordAdminAction(ctx context.Context, actorID uint, action string, client ClientInfo, details string) {
+	entry := &models.AuthLog{
+		UserID:    actorID,
+		EventType: action,
+		IPAddress: net.ParseIP(client.IPAddress),
+		UserAgent: client.UserAgent,
+		Success:   true,
+		Details:   details,
+	}
+
+	if err := s.logs.Create(ctx, entry); err != nil {
+		log.Printf("Failed to write auth log %q for user %d: %v", action, actorID, err)
+	}
+}
+
+// RecordDecision writes an audit entry for an authorization check made for userID
+func (s *AuthLogService) RecordDecision(ctx context.Context, userID uint, client ClientInfo, allowed bool, reason, details string) {
+	entry := &models.AuthLog{
+		UserID:    userID,
+		EventType: "authz_decision",
+		IPAddress: net.ParseIP(client.IPAddress),
+		UserAgent: client.UserAgent,
+		Success:   allowed,
+		Details:   details,
+	}
+	if !allowed {
+		entry.ErrorMessage = reason
+	}
+
+	if err := s.logs.Create(ctx, entry); err != nil {
+		log.Printf("Failed to write authorization decision for user %d: %v", userID, err)
+	}
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"errors"
+	"github.com/SAP-2025/auth-service/internal/config"
+	"testing"
+)
+
+func TestValidateReturnTo(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Security.BFF.Enabled = true
+	cfg.Security.AllowedOrigins = []string{"https://app.example.com/", "http://localhost:3000"}
+	s := &AuthService{cfg: cfg}
+
+	tests := []struct {
+		name     string
+		returnTo string
+		want     error
+	}{
+		{"allowed origin", "https://app.example.com", nil},
+		{"path on allowed origin", "https://app.example.com/exams/42?tab=results#top", nil},
+		{"origin is case insensitive", "https://APP.example.com/home", nil},
+		{"allowed origin with port", "http://localhost:3000/dashboard", nil},
+		{"relative path", "/dashboard", ErrInvalidReturnTo},
+		{"protocol relative", "//evil.example.com/", ErrInvalidReturnTo},
+		{"other host", "https://evil.example.com/", ErrInvalidReturnTo},
+		{"allowed host as subdomain", "https://app.example.com.evil.com/", ErrInvalidReturnTo},
+		{"allowed host as userinfo", "https://app.example.com@evil.com/", ErrInvalidReturnTo},
+		{"other scheme", "http://app.example.com/", ErrInvalidReturnTo},
+		{"other port", "http://localhost:8080/", ErrInvalidReturnTo},
+		{"javascript url", "javascript:alert(1)", ErrInvalidReturnTo},
+		{"unparsable", "https://app.example.com/%zz", ErrInvalidReturnTo},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.validateReturnTo(tt.returnTo); !errors.Is(err, tt.want) {
+				t.Errorf("validateReturnTo(%q) = %v, want %v", tt.returnTo, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateReturnToRequiresBFF(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Security.AllowedOrigins = []string{"https://app.example.com"}
+	s := &AuthService{cfg: cfg}
+
+	if err := s.validateReturnTo("https://app.example.com/"); !errors.Is(err, ErrReturnToUnsupported) {
+		t.Errorf("validateReturnTo() = %v, want %v", err, ErrReturnToUnsupported)
+	}
+}
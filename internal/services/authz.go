@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/SAP-2025/auth-service/internal/config"
+	"github.com/SAP-2025/auth-service/internal/models"
+)
+
+// Decision log levels
+const (
+	DecisionLogAll  = "all"
+	DecisionLogDeny = "deny"
+	DecisionLogNone = "none"
+)
+
+// MaxBatchChecks bounds the checks of one batch request
+const MaxBatchChecks = 100
+
+var ErrInvalidCheck = errors.New("invalid authorization check")
+
+// AuthzCheck asks whether a user may perform an action on a resource
+type AuthzCheck struct {
+	Subject  AuthzSubject   `json:"subject"`
+	Action   string         `json:"action"`
+	Resource PolicyResource `json:"resource"`
+}
+
+// AuthzSubject identifies the user of a check by ID or by one of their access tokens
+type AuthzSubject struct {
+	UserID uint   `json:"user_id,omitempty"`
+	Token  string `json:"token,omitempty"`
+}
+
+// AuthzService answers the authorization checks of internal services
+type AuthzService struct {
+	engine         *PolicyEngine
+	authService    *AuthService
+	userService    *UserService
+	authLogService *AuthLogService
+	decisionLog    string
+}
+
+func NewAuthzService(engine *PolicyEngine, authService *AuthService, userService *UserService, authLogService *AuthLogService, cfg *config.Config) (*AuthzService, error) {
+	decisionLog := cfg.Authorization.DecisionLog
+	switch decisionLog {
+	case "":
+		decisionLog = DecisionLogAll
+	case DecisionLogAll, DecisionLogDeny, DecisionLogNone:
+	default:
+		return nil, fmt.Errorf("unknown decision log level %q", decisionLog)
+	}
+
+	return &AuthzService{
+		engine:         engine,
+		authService:    authService,
+		userService:    userService,
+		authLogService: authLogService,
+		decisionLog:    decisionLog,
+	}, nil
+}
+
+// Validate rejects checks missing the subject, action or resource type
+func (c *AuthzCheck) Validate() error {
+	if c.Subject.UserID == 0 && c.Subject.Token == "" {
+		return fmt.Errorf("%w: subject.user_id or subject.token is required", ErrInvalidCheck)
+	}
+	if c.Action == "" || c.Resource.Type == "" {
+		return fmt.Errorf("%w: action and resource.type are required", ErrInvalidCheck)
+	}
+	return nil
+}
+
+// Check decides a single check on behalf of clientID
+func (s *AuthzService) Check(ctx context.Context, check *AuthzCheck, clientID string, client ClientInfo) (PolicyDecision, error) {
+	if err := check.Validate(); err != nil {
+		return PolicyDecision{}, err
+	}
+	return s.decide(ctx, check, clientID, client)
+}
+
+// CheckBatch decides every check, in order; an invalid check fails the whole batch
+func (s *AuthzService) CheckBatch(ctx context.Context, checks []AuthzCheck, clientID string, client ClientInfo) ([]PolicyDecision, error) {
+	if len(checks) == 0 || len(checks) > MaxBatchChecks {
+		return nil, fmt.Errorf("%w: a batch holds 1 to %d checks", ErrInvalidCheck, MaxBatchChecks)
+	}
+	for i := range checks {
+		if err := checks[i].Validate(); err != nil {
+			return nil, fmt.Errorf("check %d: %w", i, err)
+		}
+	}
+
+	decisions := make([]PolicyDecision, 0, len(checks))
+	for i := range checks {
+		decision, err := s.decide(ctx, &checks[i], clientID, client)
+		if err != nil {
+			return nil, err
+		}
+		decisions = append(decisions, decision)
+	}
+	return decisions, nil
+}
+
+func (s *AuthzService) decide(ctx context.Context, check *AuthzCheck, clientID string, client ClientInfo) (PolicyDecision, error) {
+	user, reason, err := s.resolveSubject(ctx, check.Subject)
+	if err != nil {
+		return PolicyDecision{}, err
+	}
+
+	var decision PolicyDecision
+	if user == nil {
+		decision = PolicyDecision{Reason: reason}
+	} else {
+		subject := &PolicySubject{
+			ID:           user.ID,
+			Username:     user.Username,
+			Role:         user.Role,
+			Organization: user.Organization,
+		}
+		decision = s.engine.Evaluate(subject, check.Action, &check.Resource)
+	}
+
+	s.logDecision(ctx, user, check, decision, clientID, client)
+	return decision, nil
+}
+
+// resolveSubject loads the user of a check. Subjects that cannot act are
+// denied with a reason rather than failing the check.
+func (s *AuthzService) resolveSubject(ctx context.Context, subject AuthzSubject) (*models.User, string, error) {
+	userID := subject.UserID
+	if subject.Token != "" {
+		claims, err := s.authService.Authenticate(ctx, subject.Token)
+		if err != nil {
+			return nil, "subject token is invalid", nil
+		}
+		userID, err = claims.UserID()
+		if err != nil {
+			return nil, "subject token is invalid", nil
+		}
+	}
+
+	user, err := s.userService.GetByID(ctx, userID)
+	if errors.Is(err, ErrUserNotFound) {
+		return nil, "unknown subject", nil
+	} else if err != nil {
+		return nil, "", err
+	}
+	if !user.IsActive {
+		return nil, "subject is inactive", nil
+	}
+	return user, "", nil
+}
+
+func (s *AuthzService) logDecision(ctx context.Context, user *models.User, check *AuthzCheck, decision PolicyDecision, clientID string, client ClientInfo) {
+	if s.decisionLog == DecisionLogNone || (s.decisionLog == DecisionLogDeny && decision.Allowed) {
+		return
+	}
+
+	var userID uint
+	if user != nil {
+		userID = user.ID
+	}
+	details := fmt.Sprintf("client=%s action=%s resource=%s/%s policy=%s@%d",
+		clientID, check.Action, check.Resource.Type, check.Resource.ID, decision.PolicyID, decision.PolicyVersion)
+	s.authLogService.RecordDecision(ctx, userID, client, decision.Allowed, decision.Reason, details)
+}
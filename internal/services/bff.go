@@ -0,0 +1,236 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/SAP-2025/auth-service/internal/config"
+	"github.com/SAP-2025/auth-service/internal/utils"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrBFFSessionNotFound = errors.New("session not found")
+
+// refreshLeeway renews access tokens shortly before they expire so a token
+// never runs out while a request is in flight
+const refreshLeeway = 30 * time.Second
+
+// BFFSession holds the tokens of a browser session in backend-for-frontend mode
+type BFFSession struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	SessionID    string    `json:"session_id"`
+	UserID       uint      `json:"user_id"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// BFFSessionStore keeps the tokens of browser sessions in Redis, keyed by the
+// hash of an opaque cookie value, and refreshes them transparently
+type BFFSessionStore struct {
+	client      *redis.Client
+	authService *AuthService
+	enabled     bool
+	cookieName  string
+	ttl         time.Duration
+}
+
+func NewBFFSessionStore(client *redis.Client, authService *AuthService, cfg *config.Config) *BFFSessionStore {
+	return &BFFSessionStore{
+		client:      client,
+		authService: authService,
+		enabled:     cfg.Security.BFF.Enabled,
+		cookieName:  cfg.BFFCookieName(),
+		ttl:         cfg.RefreshTokenTTL(),
+	}
+}
+
+// Enabled reports whether tokens are kept server side
+func (s *BFFSessionStore) Enabled() bool {
+	return s.enabled
+}
+
+func (s *BFFSessionStore) CookieName() string {
+	return s.cookieName
+}
+
+// TTL returns how long a session cookie stays valid
+func (s *BFFSessionStore) TTL() time.Duration {
+	return s.ttl
+}
+
+// Create stores the tokens of a login and returns the cookie value identifying them
+func (s *BFFSessionStore) Create(ctx context.Context, tokens *CallbackResponse) (string, error) {
+	cookie, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	session := &BFFSession{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		SessionID:    tokens.SessionID,
+		UserID:       tokens.User.ID,
+		ExpiresAt:    time.Now().Add(time.Duration(tokens.ExpiresIn) * time.Second),
+	}
+
+	data, err := json.Marshal(session)
+	if err != nil {
+		return "", err
+	}
+	if err := s.client.Set(ctx, s.getSessionKey(cookie), data, s.ttl).Err(); err != nil {
+		return "", fmt.Errorf("failed to store session: %w", err)
+	}
+
+	return cookie, nil
+}
+
+// Resolve returns the claims of the session identified by cookie, refreshing
+// the access token when it is about to expire
+func (s *BFFSessionStore) Resolve(ctx context.Context, cookie string, client ClientInfo) (*AccessClaims, error) {
+	key := s.getSessionKey(cookie)
+	session, err := s.load(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if time.Now().Add(refreshLeeway).After(session.ExpiresAt) {
+		session, err = s.refresh(ctx, key, session, client)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	claims, err := s.authService.Authenticate(ctx, session.AccessToken)
+	if err != nil {
+		s.drop(ctx, key, err)
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// Refresh renews the tokens of the session identified by cookie right away
+func (s *BFFSessionStore) Refresh(ctx context.Context, cookie string, client ClientInfo) (*BFFSession, error) {
+	key := s.getSessionKey(cookie)
+	session, err := s.load(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return s.refresh(ctx, key, session, client)
+}
+
+// Delete removes the session identified by cookie and returns its tokens
+func (s *BFFSessionStore) Delete(ctx context.Context, cookie string) (*BFFSession, error) {
+	data, err := s.client.GetDel(ctx, s.getSessionKey(cookie)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrBFFSessionNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to delete session: %w", err)
+	}
+
+	var session BFFSession
+	if err := json.Unmarshal([]byte(data), &session); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal session: %w", err)
+	}
+	return &session, nil
+}
+
+// refresh rotates the tokens of session. Concurrent requests of the same browser
+// must not present the same refresh token twice, which would count as reuse and
+// revoke the session, so only the request holding the lock refreshes.
+func (s *BFFSessionStore) refresh(ctx context.Context, key string, session *BFFSession, client ClientInfo) (*BFFSession, error) {
+	lockKey := key + ":lock"
+	acquired, err := s.client.SetNX(ctx, lockKey, 1, 10*time.Second).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock session: %w", err)
+	}
+	if !acquired {
+		return s.waitForRefresh(ctx, key, session.RefreshToken)
+	}
+	defer s.client.Del(ctx, lockKey)
+
+	// Another request may have finished refreshing before the lock was taken
+	current, err := s.load(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if current.RefreshToken != session.RefreshToken {
+		return current, nil
+	}
+
+	tokens, err := s.authService.RefreshToken(ctx, current.RefreshToken, client)
+	if err != nil {
+		s.drop(ctx, key, err)
+		return nil, err
+	}
+
+	current.AccessToken = tokens.AccessToken
+	current.RefreshToken = tokens.RefreshToken
+	current.ExpiresAt = time.Now().Add(time.Duration(tokens.ExpiresIn) * time.Second)
+
+	data, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.client.Set(ctx, key, data, redis.KeepTTL).Err(); err != nil {
+		return nil, fmt.Errorf("failed to store session: %w", err)
+	}
+
+	return current, nil
+}
+
+// waitForRefresh polls until the request holding the lock stored new tokens
+func (s *BFFSessionStore) waitForRefresh(ctx context.Context, key, oldRefreshToken string) (*BFFSession, error) {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(10 * time.Second)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timeout:
+			return nil, errors.New("timed out waiting for session refresh")
+		case <-ticker.C:
+			session, err := s.load(ctx, key)
+			if err != nil {
+				return nil, err
+			}
+			if session.RefreshToken != oldRefreshToken {
+				return session, nil
+			}
+		}
+	}
+}
+
+// drop deletes the session once its tokens were rejected for good
+func (s *BFFSessionStore) drop(ctx context.Context, key string, err error) {
+	if errors.Is(err, ErrTokenRevoked) || errors.Is(err, ErrInvalidRefreshToken) ||
+		errors.Is(err, ErrRefreshTokenExpired) || errors.Is(err, ErrRefreshTokenReused) ||
+		errors.Is(err, ErrRoleUnmapped) || errors.Is(err, ErrUserInactive) {
+		s.client.Del(ctx, key)
+	}
+}
+
+func (s *BFFSessionStore) load(ctx context.Context, key string) (*BFFSession, error) {
+	data, err := s.client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrBFFSessionNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	var session BFFSession
+	if err := json.Unmarshal([]byte(data), &session); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal session: %w", err)
+	}
+	return &session, nil
+}
+
+// Helper function to generate Redis key
+func (s *BFFSessionStore) getSessionKey(cookie string) string {
+	return fmt.Sprintf("bff:session:%s", utils.HashToken(cookie))
+}
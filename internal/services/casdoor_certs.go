@@ -0,0 +1,322 @@
+package services
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/SAP-2025/auth-service/internal/config"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/casdoor/casdoor-go-sdk/casdoorsdk"
+)
+
+var ErrNoCasdoorCert = errors.New("no Casdoor certificate configured")
+
+// casdoorJWK is a key of the Casdoor JWKS document
+type casdoorJWK struct {
+	Kty string   `json:"kty"`
+	Kid string   `json:"kid"`
+	Crv string   `json:"crv"`
+	N   string   `json:"n"`
+	E   string   `json:"e"`
+	X   string   `json:"x"`
+	Y   string   `json:"y"`
+	X5c []string `json:"x5c"`
+}
+
+// CasdoorCertManager holds the certificates Casdoor tokens are verified with.
+// Certificates come from OAuth2.Casdoor.Cert (a file path or inline PEM) and
+// from the Casdoor JWKS endpoint, and are reloaded periodically. All of them
+// are accepted, so tokens keep verifying while Casdoor rotates its key.
+type CasdoorCertManager struct {
+	cert       string
+	jwksURL    string
+	interval   time.Duration
+	httpClient *http.Client
+
+	mu    sync.RWMutex
+	certs []string
+	keys  []crypto.PublicKey
+	// last certificates each source loaded successfully
+	lastLocal  []string
+	lastRemote []string
+}
+
+func NewCasdoorCertManager(cfg *config.Config) *CasdoorCertManager {
+	interval := cfg.OAuth2.Casdoor.CertRefreshInterval
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	return &CasdoorCertManager{
+		cert:       cfg.OAuth2.Casdoor.Cert,
+		jwksURL:    cfg.CasdoorJWKSURL(),
+		interval:   interval,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Load reads the configured certificates. A source that fails keeps its own
+// previous certificates; Load only fails when no certificate is left at all.
+func (m *CasdoorCertManager) Load(ctx context.Context) error {
+	m.mu.RLock()
+	local, remote := m.lastLocal, m.lastRemote
+	m.mu.RUnlock()
+
+	if m.cert != "" {
+		certs, err := m.loadConfigured()
+		if err != nil {
+			log.Printf("Failed to load Casdoor certificate: %v", err)
+		} else {
+			local = certs
+		}
+	}
+
+	if m.jwksURL != "" {
+		certs, err := m.fetchJWKS(ctx)
+		if err != nil {
+			log.Printf("Failed to fetch Casdoor JWKS: %v", err)
+		} else {
+			remote = certs
+		}
+	}
+
+	certs := dedupe(append(append([]string(nil), local...), remote...))
+	if len(certs) == 0 {
+		return ErrNoCasdoorCert
+	}
+
+	keys := make([]crypto.PublicKey, 0, len(certs))
+	for _, cert := range certs {
+		key, err := parsePublicKeyPEM(cert)
+		if err != nil {
+			log.Printf("Skipping unreadable Casdoor certificate: %v", err)
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	m.mu.Lock()
+	m.certs = certs
+	m.keys = keys
+	m.lastLocal = local
+	m.lastRemote = remote
+	m.mu.Unlock()
+
+	return nil
+}
+
+// Run reloads the certificates every refresh interval until ctx is cancelled
+func (m *CasdoorCertManager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.Load(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("Casdoor certificate refresh failed: %v", err)
+			}
+		}
+	}
+}
+
+// ParseJwtToken verifies a Casdoor token against every known certificate
+func (m *CasdoorCertManager) ParseJwtToken(token string) (*casdoorsdk.Claims, error) {
+	certs := m.current()
+	if len(certs) == 0 {
+		return nil, ErrNoCasdoorCert
+	}
+
+	var lastErr error
+	for _, cert := range certs {
+		client := casdoorsdk.Client{AuthConfig: casdoorsdk.AuthConfig{Certificate: cert}}
+		claims, err := client.ParseJwtToken(token)
+		if err == nil {
+			return claims, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// PublicKeys returns the public keys of every known certificate
+func (m *CasdoorCertManager) PublicKeys() []crypto.PublicKey {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.keys
+}
+
+func (m *CasdoorCertManager) current() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.certs
+}
+
+// loadConfigured returns the PEM blocks of the Cert setting, which is either
+// inline PEM or the path of a PEM file
+func (m *CasdoorCertManager) loadConfigured() ([]string, error) {
+	data := []byte(m.cert)
+	if !strings.Contains(m.cert, "-----BEGIN") {
+		var err error
+		data, err = os.ReadFile(m.cert)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var certs []string
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		certs = append(certs, string(pem.EncodeToMemory(block)))
+	}
+
+	if len(certs) == 0 {
+		return nil, errors.New("no PEM block found")
+	}
+	return certs, nil
+}
+
+func (m *CasdoorCertManager) fetchJWKS(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.jwksURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []casdoorJWK `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	var certs []string
+	for _, key := range set.Keys {
+		cert, err := key.toPEM()
+		if err != nil {
+			log.Printf("Skipping Casdoor key %s: %v", key.Kid, err)
+			continue
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, errors.New("JWKS contains no usable key")
+	}
+	return certs, nil
+}
+
+// toPEM prefers the certificate of the key and falls back to its raw public key
+func (k casdoorJWK) toPEM() (string, error) {
+	if len(k.X5c) > 0 {
+		der, err := base64.StdEncoding.DecodeString(k.X5c[0])
+		if err != nil {
+			return "", err
+		}
+		return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})), nil
+	}
+
+	decode := base64.RawURLEncoding.DecodeString
+	var public interface{}
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return "", err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return "", err
+		}
+		public = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return "", fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return "", err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return "", err
+		}
+		public = &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	default:
+		return "", fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
+
+func parsePublicKeyPEM(data string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("invalid PEM")
+	}
+
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	}
+}
+
+func dedupe(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := values[:0]
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,31 @@
+package services
+
+import (
+	"crypto/subtle"
+	"github.com/SAP-2025/auth-service/internal/config"
+)
+
+// ClientRegistry authenticates the internal services configured in OAuth2.Clients
+type ClientRegistry struct {
+	clients map[string]config.OAuthClient
+}
+
+func NewClientRegistry(cfg *config.Config) *ClientRegistry {
+	clients := make(map[string]config.OAuthClient, len(cfg.OAuth2.Clients))
+	for _, client := range cfg.OAuth2.Clients {
+		clients[client.ID] = client
+	}
+	return &ClientRegistry{clients: clients}
+}
+
+// Authenticate checks the client credentials and returns the matching client
+func (r *ClientRegistry) Authenticate(clientID, clientSecret string) (*config.OAuthClient, bool) {
+	client, ok := r.clients[clientID]
+	if !ok || client.Secret == "" {
+		return nil, false
+	}
+	if subtle.ConstantTimeCompare([]byte(client.Secret), []byte(clientSecret)) != 1 {
+		return nil, false
+	}
+	return &client, true
+}
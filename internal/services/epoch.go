@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RevocationEpochService is the kill switch invalidating every token issued
+// before a point in time, globally or for a single organization
+type RevocationEpochService struct {
+	revokedTokens  *TokenRevocationStore
+	authLogService *AuthLogService
+	eventService   *EventService
+}
+
+func NewRevocationEpochService(revokedTokens *TokenRevocationStore, authLogService *AuthLogService, eventService *EventService) *RevocationEpochService {
+	return &RevocationEpochService{
+		revokedTokens:  revokedTokens,
+		authLogService: authLogService,
+		eventService:   eventService,
+	}
+}
+
+// Bump invalidates every token issued so far, for organization or globally
+// when organization is empty, and audits the action as actorID
+func (s *RevocationEpochService) Bump(ctx context.Context, actorID uint, organization string, client ClientInfo) (time.Time, error) {
+	epoch, err := s.revokedTokens.BumpEpoch(ctx, organization)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	scope := "global"
+	if organization != "" {
+		scope = "organization " + organization
+	}
+	log.Printf("Revocation epoch (%s) bumped to %s by user %d", scope, epoch.Format(time.RFC3339), actorID)
+	s.authLogService.RecordAdminAction(ctx, actorID, "revocation_epoch_bumped", client, scope)
+
+	if err := s.eventService.PublishRevocationEpochEvent(actorID, organization, epoch); err != nil {
+		log.Printf("Failed to publish revocation epoch event: %v", err)
+	}
+
+	return epoch, nil
+}
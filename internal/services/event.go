@@ -3,6 +3,7 @@ package services
 import (
 	"encoding/json"
 	"github.com/SAP-2025/auth-service/internal/config"
+	"github.com/SAP-2025/auth-service/internal/models"
 	"github.com/ThreeDotsLabs/watermill-kafka/v3/pkg/kafka"
 	"time"
 
@@ -52,6 +53,31 @@ func (e *EventService) PublishLoginEvent(userID uint, username, role, loginMetho
 	return e.PublishEvent("auth.user.login", data)
 }
 
+func (e *EventService) PublishUserCreatedEvent(user *models.User) error {
+	data := map[string]interface{}{
+		"userId":        user.ID,
+		"casdoorUserId": user.CasdoorUserID,
+		"username":      user.Username,
+		"email":         user.Email,
+		"name":          user.Name,
+		"role":          user.Role,
+		"organization":  user.Organization,
+		"timestamp":     time.Now().UTC().Format(time.RFC3339),
+	}
+	return e.PublishEvent("auth.user.created", data)
+}
+
+// PublishUserDeactivatedEvent tells other services to stop serving a user an admin deactivated
+func (e *EventService) PublishUserDeactivatedEvent(userID, actorID uint, revokedSessions int) error {
+	data := map[string]interface{}{
+		"userId":          userID,
+		"actorId":         actorID,
+		"revokedSessions": revokedSessions,
+		"timestamp":       time.Now().UTC().Format(time.RFC3339),
+	}
+	return e.PublishEvent("auth.user.deactivated", data)
+}
+
 func (e *EventService) PublishLogoutEvent(userID uint, sessionID, reason, ip, ua string) error {
 	data := map[string]interface{}{
 		"userId":    userID,
@@ -62,6 +88,25 @@ func (e *EventService) PublishLogoutEvent(userID uint, sessionID, reason, ip, ua
 	return e.PublishEvent("auth.user.logout", data)
 }
 
+// PublishSessionsExpiredEvent reports a batch of expired sessions in one event,
+// a large backlog must not flood the topic with one logout event per session
+func (e *EventService) PublishSessionsExpiredEvent(sessions []models.UserSession) error {
+	expired := make([]map[string]interface{}, 0, len(sessions))
+	for _, session := range sessions {
+		expired = append(expired, map[string]interface{}{
+			"userId":    session.UserID,
+			"sessionId": session.ID.String(),
+		})
+	}
+
+	data := map[string]interface{}{
+		"sessions":  expired,
+		"reason":    LogoutReasonExpired,
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	}
+	return e.PublishEvent("auth.sessions.expired", data)
+}
+
 func (e *EventService) PublishTokenRefreshedEvent(userID uint, sessionID, ip, ua string) error {
 	data := map[string]interface{}{
 		"userId":    userID,
@@ -70,3 +115,36 @@ func (e *EventService) PublishTokenRefreshedEvent(userID uint, sessionID, ip, ua
 	}
 	return e.PublishEvent("auth.token.refreshed", data)
 }
+
+func (e *EventService) PublishTokenRevokedEvent(userID uint, sessionID, jti string, expiresAt time.Time) error {
+	data := map[string]interface{}{
+		"userId":    userID,
+		"sessionId": sessionID,
+		"jti":       jti,
+		"expiresAt": expiresAt.UTC().Format(time.RFC3339),
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	}
+	return e.PublishEvent("auth.token.revoked", data)
+}
+
+func (e *EventService) PublishRevocationEpochEvent(actorID uint, organization string, epoch time.Time) error {
+	data := map[string]interface{}{
+		"actorId":      actorID,
+		"organization": organization,
+		"epoch":        epoch.UTC().Format(time.RFC3339),
+		"timestamp":    time.Now().UTC().Format(time.RFC3339),
+	}
+	return e.PublishEvent("auth.revocation_epoch.bumped", data)
+}
+
+func (e *EventService) PublishTokenReuseDetectedEvent(userID uint, sessionID string, revokedSessions int64, ip, ua string) error {
+	data := map[string]interface{}{
+		"userId":          userID,
+		"sessionId":       sessionID,
+		"revokedSessions": revokedSessions,
+		"ipAddress":       ip,
+		"userAgent":       ua,
+		"timestamp":       time.Now().UTC().Format(time.RFC3339),
+	}
+	return e.PublishEvent("auth.token.reuse_detected", data)
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"github.com/SAP-2025/auth-service/internal/config"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Reasons an ID token is rejected, recorded in the auth log
+const (
+	IDTokenMissing          = "id_token_missing"
+	IDTokenMalformed        = "id_token_malformed"
+	IDTokenInvalidSignature = "id_token_invalid_signature"
+	IDTokenInvalidIssuer    = "id_token_invalid_issuer"
+	IDTokenInvalidAudience  = "id_token_invalid_audience"
+	IDTokenInvalidAZP       = "id_token_invalid_azp"
+	IDTokenInvalidNonce     = "id_token_invalid_nonce"
+	IDTokenExpired          = "id_token_expired"
+	IDTokenIssuedInFuture   = "id_token_issued_in_future"
+	IDTokenSubjectMismatch  = "id_token_subject_mismatch"
+)
+
+// IDTokenError is an ID token failing verification, Code names the failed check
+type IDTokenError struct {
+	Code string
+	Err  error
+}
+
+func (e *IDTokenError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("invalid id_token (%s): %v", e.Code, e.Err)
+	}
+	return fmt.Sprintf("invalid id_token (%s)", e.Code)
+}
+
+func (e *IDTokenError) Unwrap() error {
+	return e.Err
+}
+
+// IDTokenClaims are the claims of a Casdoor ID token we check
+type IDTokenClaims struct {
+	Nonce           string `json:"nonce"`
+	AuthorizedParty string `json:"azp"`
+	jwt.RegisteredClaims
+}
+
+// IDTokenVerifier checks Casdoor ID tokens as OpenID Connect Core 3.1.3.7 requires
+type IDTokenVerifier struct {
+	certs    *CasdoorCertManager
+	issuer   string
+	clientID string
+	skew     time.Duration
+}
+
+func NewIDTokenVerifier(certs *CasdoorCertManager, cfg *config.Config) *IDTokenVerifier {
+	issuer := cfg.OAuth2.Casdoor.Issuer
+	if issuer == "" {
+		issuer = cfg.OAuth2.Casdoor.BaseURL
+	}
+
+	skew := cfg.OAuth2.Casdoor.ClockSkew
+	if skew <= 0 {
+		skew = 30 * time.Second
+	}
+
+	return &IDTokenVerifier{
+		certs:    certs,
+		issuer:   strings.TrimSuffix(issuer, "/"),
+		clientID: cfg.OAuth2.Casdoor.ClientID,
+		skew:     skew,
+	}
+}
+
+// Verify checks the signature and claims of rawIDToken against the nonce sent
+// in the authorization request
+func (v *IDTokenVerifier) Verify(rawIDToken, nonce string) (*IDTokenClaims, error) {
+	if rawIDToken == "" {
+		return nil, &IDTokenError{Code: IDTokenMissing}
+	}
+
+	// Claims are checked below so every failure gets its own code
+	claims := &IDTokenClaims{}
+	_, err := jwt.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		keys := v.certs.PublicKeys()
+		if len(keys) == 0 {
+			return nil, ErrNoCasdoorCert
+		}
+		set := jwt.VerificationKeySet{Keys: make([]jwt.VerificationKey, 0, len(keys))}
+		for _, key := range keys {
+			set.Keys = append(set.Keys, key)
+		}
+		return set, nil
+	}, jwt.WithValidMethods([]string{"RS256", "RS512", "ES256", "ES512"}), jwt.WithoutClaimsValidation())
+	if errors.Is(err, jwt.ErrTokenMalformed) {
+		return nil, &IDTokenError{Code: IDTokenMalformed, Err: err}
+	} else if err != nil {
+		return nil, &IDTokenError{Code: IDTokenInvalidSignature, Err: err}
+	}
+
+	if strings.TrimSuffix(claims.Issuer, "/") != v.issuer {
+		return nil, &IDTokenError{Code: IDTokenInvalidIssuer, Err: fmt.Errorf("issuer %q", claims.Issuer)}
+	}
+
+	if !slices.Contains(claims.Audience, v.clientID) {
+		return nil, &IDTokenError{Code: IDTokenInvalidAudience, Err: fmt.Errorf("audience %v", claims.Audience)}
+	}
+
+	// A token for several audiences must name us as the party it was issued to
+	if (len(claims.Audience) > 1 || claims.AuthorizedParty != "") && claims.AuthorizedParty != v.clientID {
+		return nil, &IDTokenError{Code: IDTokenInvalidAZP, Err: fmt.Errorf("azp %q", claims.AuthorizedParty)}
+	}
+
+	if nonce == "" || subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, &IDTokenError{Code: IDTokenInvalidNonce}
+	}
+
+	if claims.ExpiresAt == nil || claims.IssuedAt == nil {
+		return nil, &IDTokenError{Code: IDTokenMalformed, Err: errors.New("missing exp or iat")}
+	}
+
+	now := time.Now()
+	if !now.Before(claims.ExpiresAt.Add(v.skew)) {
+		return nil, &IDTokenError{Code: IDTokenExpired}
+	}
+	if claims.IssuedAt.After(now.Add(v.skew)) {
+		return nil, &IDTokenError{Code: IDTokenIssuedInFuture}
+	}
+
+	return claims, nil
+}
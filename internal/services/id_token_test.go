@@ -0,0 +1,172 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestIDTokenVerifierVerify(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	v := &IDTokenVerifier{
+		certs:    &CasdoorCertManager{keys: []crypto.PublicKey{&key.PublicKey}},
+		issuer:   "https://casdoor.example.com",
+		clientID: "client-1",
+		skew:     30 * time.Second,
+	}
+	now := time.Now()
+
+	// valid returns the claims of a token that passes every check
+	valid := func() IDTokenClaims {
+		return IDTokenClaims{
+			Nonce: "nonce-1",
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    "https://casdoor.example.com/",
+				Subject:   "user-1",
+				Audience:  jwt.ClaimStrings{"client-1"},
+				ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+				IssuedAt:  jwt.NewNumericDate(now),
+			},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(c *IDTokenClaims)
+		signer *ecdsa.PrivateKey
+		nonce  string
+		want   string
+	}{
+		{name: "valid token"},
+		{
+			name:   "wrong issuer",
+			modify: func(c *IDTokenClaims) { c.Issuer = "https://evil.example.com" },
+			want:   IDTokenInvalidIssuer,
+		},
+		{
+			name:   "audience without the client id",
+			modify: func(c *IDTokenClaims) { c.Audience = jwt.ClaimStrings{"client-2", "client-3"} },
+			want:   IDTokenInvalidAudience,
+		},
+		{
+			name:   "several audiences without azp",
+			modify: func(c *IDTokenClaims) { c.Audience = jwt.ClaimStrings{"client-1", "client-2"} },
+			want:   IDTokenInvalidAZP,
+		},
+		{
+			name: "several audiences with another azp",
+			modify: func(c *IDTokenClaims) {
+				c.Audience = jwt.ClaimStrings{"client-1", "client-2"}
+				c.AuthorizedParty = "client-2"
+			},
+			want: IDTokenInvalidAZP,
+		},
+		{
+			name: "several audiences with our azp",
+			modify: func(c *IDTokenClaims) {
+				c.Audience = jwt.ClaimStrings{"client-1", "client-2"}
+				c.AuthorizedParty = "client-1"
+			},
+		},
+		{
+			name:  "nonce mismatch",
+			nonce: "nonce-2",
+			want:  IDTokenInvalidNonce,
+		},
+		{
+			name:   "expired",
+			modify: func(c *IDTokenClaims) { c.ExpiresAt = jwt.NewNumericDate(now.Add(-time.Minute)) },
+			want:   IDTokenExpired,
+		},
+		{
+			name:   "expired within the clock skew",
+			modify: func(c *IDTokenClaims) { c.ExpiresAt = jwt.NewNumericDate(now.Add(-10 * time.Second)) },
+		},
+		{
+			name:   "issued in the future beyond the clock skew",
+			modify: func(c *IDTokenClaims) { c.IssuedAt = jwt.NewNumericDate(now.Add(time.Minute)) },
+			want:   IDTokenIssuedInFuture,
+		},
+		{
+			name:   "issued in the future within the clock skew",
+			modify: func(c *IDTokenClaims) { c.IssuedAt = jwt.NewNumericDate(now.Add(10 * time.Second)) },
+		},
+		{
+			name:   "missing iat",
+			modify: func(c *IDTokenClaims) { c.IssuedAt = nil },
+			want:   IDTokenMalformed,
+		},
+		{
+			name:   "signed with an unknown key",
+			signer: otherKey,
+			want:   IDTokenInvalidSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := valid()
+			if tt.modify != nil {
+				tt.modify(&claims)
+			}
+			signer := key
+			if tt.signer != nil {
+				signer = tt.signer
+			}
+			nonce := "nonce-1"
+			if tt.nonce != "" {
+				nonce = tt.nonce
+			}
+
+			raw, err := jwt.NewWithClaims(jwt.SigningMethodES256, claims).SignedString(signer)
+			if err != nil {
+				t.Fatalf("SignedString() error = %v", err)
+			}
+
+			_, err = v.Verify(raw, nonce)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("Verify() error = %v, want nil", err)
+				}
+				return
+			}
+			var idErr *IDTokenError
+			if !errors.As(err, &idErr) || idErr.Code != tt.want {
+				t.Errorf("Verify() error = %v, want code %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestIDTokenVerifierRejectsMissingAndMalformed(t *testing.T) {
+	v := &IDTokenVerifier{certs: &CasdoorCertManager{}, issuer: "https://casdoor.example.com", clientID: "client-1"}
+
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"", IDTokenMissing},
+		{"not-a-jwt", IDTokenMalformed},
+	}
+
+	for _, tt := range tests {
+		var idErr *IDTokenError
+		if _, err := v.Verify(tt.raw, "nonce-1"); !errors.As(err, &idErr) || idErr.Code != tt.want {
+			t.Errorf("Verify(%q) error = %v, want code %s", tt.raw, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/SAP-2025/auth-service/internal/config"
+	"github.com/SAP-2025/auth-service/internal/utils"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// IntrospectionResponse is the RFC 7662 introspection response
+type IntrospectionResponse struct {
+	Active       bool     `json:"active"`
+	Scope        string   `json:"scope,omitempty"`
+	Username     string   `json:"username,omitempty"`
+	TokenType    string   `json:"token_type,omitempty"`
+	Exp          int64    `json:"exp,omitempty"`
+	Iat          int64    `json:"iat,omitempty"`
+	Nbf          int64    `json:"nbf,omitempty"`
+	Sub          string   `json:"sub,omitempty"`
+	Aud          []string `json:"aud,omitempty"`
+	Iss          string   `json:"iss,omitempty"`
+	Jti          string   `json:"jti,omitempty"`
+	Role         string   `json:"role,omitempty"`
+	Organization string   `json:"org,omitempty"`
+	SessionID    string   `json:"sid,omitempty"`
+}
+
+var inactiveToken = &IntrospectionResponse{Active: false}
+
+type IntrospectionService struct {
+	tokenService   *TokenService
+	sessionService *SessionService
+	userService    *UserService
+	revokedTokens  *TokenRevocationStore
+	redis          *redis.Client
+	cacheTTL       time.Duration
+}
+
+func NewIntrospectionService(tokenService *TokenService, sessionService *SessionService, userService *UserService, revokedTokens *TokenRevocationStore, redisClient *redis.Client, cfg *config.Config) *IntrospectionService {
+	cacheTTL := cfg.OAuth2.IntrospectionCacheTTL
+	if cacheTTL <= 0 {
+		cacheTTL = 30 * time.Second
+	}
+
+	return &IntrospectionService{
+		tokenService:   tokenService,
+		sessionService: sessionService,
+		userService:    userService,
+		revokedTokens:  revokedTokens,
+		redis:          redisClient,
+		cacheTTL:       cacheTTL,
+	}
+}
+
+// Introspect reports whether token is currently active. Access tokens are
+// checked first unless tokenTypeHint says refresh_token.
+func (s *IntrospectionService) Introspect(ctx context.Context, token, tokenTypeHint string) (*IntrospectionResponse, error) {
+	if tokenTypeHint == "refresh_token" {
+		return s.introspectRefreshToken(ctx, token)
+	}
+
+	resp, err := s.introspectAccessToken(ctx, token)
+	if err != nil || resp.Active || tokenTypeHint == "access_token" {
+		return resp, err
+	}
+	return s.introspectRefreshToken(ctx, token)
+}
+
+func (s *IntrospectionService) introspectAccessToken(ctx context.Context, token string) (*IntrospectionResponse, error) {
+	tokenHash := utils.HashToken(token)
+
+	// Revocation and deactivation are never cached so they take effect immediately
+	if cached, ok := s.getCached(ctx, tokenHash); ok {
+		if !cached.Active {
+			return cached, nil
+		}
+		return s.checkStillActive(ctx, cached)
+	}
+
+	claims, err := s.tokenService.ParseAccessToken(token)
+	if err != nil {
+		return inactiveToken, nil
+	}
+
+	userID, err := claims.UserID()
+	if err != nil {
+		return inactiveToken, nil
+	}
+
+	resp := inactiveToken
+	user, err := s.userService.GetByID(ctx, userID)
+	if err != nil && !errors.Is(err, ErrUserNotFound) {
+		return nil, err
+	}
+	if err == nil && user.IsActive {
+		resp = &IntrospectionResponse{
+			Active:       true,
+			Scope:        claims.Scope,
+			Username:     claims.Username,
+			TokenType:    "Bearer",
+			Exp:          claims.ExpiresAt.Unix(),
+			Sub:          claims.Subject,
+			Aud:          claims.Audience,
+			Iss:          claims.Issuer,
+			Jti:          claims.ID,
+			Role:         user.Role,
+			Organization: user.Organization,
+			SessionID:    claims.SessionID,
+		}
+		if claims.IssuedAt != nil {
+			resp.Iat = claims.IssuedAt.Unix()
+		}
+		if claims.NotBefore != nil {
+			resp.Nbf = claims.NotBefore.Unix()
+		}
+	}
+
+	ttl := s.cacheTTL
+	if remaining := time.Until(claims.ExpiresAt.Time); remaining < ttl {
+		ttl = remaining
+	}
+	s.setCached(ctx, tokenHash, resp, ttl)
+
+	if !resp.Active {
+		return resp, nil
+	}
+	return s.checkStillActive(ctx, resp)
+}
+
+// checkStillActive rejects an active response whose token has been revoked
+// or whose user has been deactivated since
+func (s *IntrospectionService) checkStillActive(ctx context.Context, resp *IntrospectionResponse) (*IntrospectionResponse, error) {
+	userID, err := strconv.ParseUint(resp.Sub, 10, 64)
+	if err != nil {
+		return inactiveToken, nil
+	}
+	active, err := s.userService.IsActive(ctx, uint(userID))
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		return inactiveToken, nil
+	}
+
+	revoked, err := s.revokedTokens.IsRevoked(ctx, resp.Jti)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return inactiveToken, nil
+	}
+
+	revoked, err = s.revokedTokens.IssuedBeforeEpoch(ctx, resp.Organization, time.Unix(resp.Iat, 0))
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return inactiveToken, nil
+	}
+	return resp, nil
+}
+
+func (s *IntrospectionService) introspectRefreshToken(ctx context.Context, token string) (*IntrospectionResponse, error) {
+	session, err := s.sessionService.GetByRefreshToken(ctx, token)
+	if errors.Is(err, ErrInvalidRefreshToken) || errors.Is(err, ErrRefreshTokenExpired) {
+		return inactiveToken, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userService.GetByID(ctx, session.UserID)
+	if errors.Is(err, ErrUserNotFound) {
+		return inactiveToken, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		return inactiveToken, nil
+	}
+
+	revoked, err := s.revokedTokens.IssuedBeforeEpoch(ctx, user.Organization, session.LastUsedAt)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return inactiveToken, nil
+	}
+
+	return &IntrospectionResponse{
+		Active:       true,
+		Username:     user.Username,
+		TokenType:    "refresh_token",
+		Exp:          session.ExpiresAt.Unix(),
+		Iat:          session.CreatedAt.Unix(),
+		Sub:          strconv.FormatUint(uint64(user.ID), 10),
+		Role:         user.Role,
+		Organization: user.Organization,
+		SessionID:    session.ID.String(),
+	}, nil
+}
+
+func (s *IntrospectionService) getCached(ctx context.Context, tokenHash string) (*IntrospectionResponse, bool) {
+	data, err := s.redis.Get(ctx, s.getCacheKey(tokenHash)).Result()
+	if err != nil {
+		return nil, false
+	}
+
+	var resp IntrospectionResponse
+	if err := json.Unmarshal([]byte(data), &resp); err != nil {
+		return nil, false
+	}
+	return &resp, true
+}
+
+func (s *IntrospectionService) setCached(ctx context.Context, tokenHash string, resp *IntrospectionResponse, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	if err := s.redis.Set(ctx, s.getCacheKey(tokenHash), data, ttl).Err(); err != nil {
+		log.Printf("Failed to cache introspection result: %v", err)
+	}
+}
+
+// Helper function to generate Redis key
+func (s *IntrospectionService) getCacheKey(tokenHash string) string {
+	return fmt.Sprintf("introspect:%s", tokenHash)
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"github.com/SAP-2025/auth-service/internal/config"
+	"log"
+	"time"
+)
+
+// SessionJanitor periodically purges expired sessions from Postgres
+type SessionJanitor struct {
+	sessionService *SessionService
+	eventService   *EventService
+	interval       time.Duration
+	batchSize      int
+}
+
+func NewSessionJanitor(sessionService *SessionService, eventService *EventService, cfg *config.Config) *SessionJanitor {
+	interval := cfg.Session.CleanupInterval
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	batchSize := cfg.Session.CleanupBatchSize
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+
+	return &SessionJanitor{
+		sessionService: sessionService,
+		eventService:   eventService,
+		interval:       interval,
+		batchSize:      batchSize,
+	}
+}
+
+// Run purges expired sessions right away and then every interval until ctx is
+// cancelled, so a restart does not delay the purge by a whole interval
+func (j *SessionJanitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	log.Printf("Session janitor started, running every %s", j.interval)
+	j.runCleanup(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Session janitor stopped")
+			return
+		case <-ticker.C:
+			j.runCleanup(ctx)
+		}
+	}
+}
+
+func (j *SessionJanitor) runCleanup(ctx context.Context) {
+	purged, err := j.Cleanup(ctx)
+	if err != nil && ctx.Err() == nil {
+		log.Printf("Session cleanup failed after purging %d sessions: %v", purged, err)
+		return
+	}
+	if purged > 0 {
+		log.Printf("Session cleanup purged %d expired sessions", purged)
+	}
+}
+
+// Cleanup deletes expired sessions batch by batch and returns how many were purged
+func (j *SessionJanitor) Cleanup(ctx context.Context) (int, error) {
+	purged := 0
+	for ctx.Err() == nil {
+		expired, err := j.sessionService.DeleteExpiredBatch(ctx, j.batchSize)
+		if err != nil {
+			return purged, err
+		}
+
+		if len(expired) > 0 {
+			if err := j.eventService.PublishSessionsExpiredEvent(expired); err != nil {
+				log.Printf("Failed to publish sessions expired event: %v", err)
+			}
+		}
+
+		purged += len(expired)
+		if len(expired) < j.batchSize {
+			break
+		}
+	}
+
+	return purged, ctx.Err()
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/SAP-2025/auth-service/internal/config"
+	"github.com/SAP-2025/auth-service/internal/models"
+	"github.com/SAP-2025/auth-service/internal/utils"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// keyRotationLockID serializes key rotation across instances sharing a database
+const keyRotationLockID = 7_254_410_008
+
+// KeyStore persists signing keys with their PEM private key, encrypted in the database
+type KeyStore interface {
+	Load(ctx context.Context) ([]models.SigningKey, error)
+	Save(ctx context.Context, key *models.SigningKey) error
+	Delete(ctx context.Context, kid string) error
+	// WithLock runs fn with a store no other instance rotates keys in meanwhile
+	WithLock(ctx context.Context, fn func(store KeyStore) error) error
+}
+
+// NewKeyStore returns the key store selected by JWT.Signing.Storage
+func NewKeyStore(db *gorm.DB, cfg *config.Config) (KeyStore, error) {
+	switch cfg.JWT.Signing.Storage {
+	case "", "database":
+		secret := cfg.EncryptionKey()
+		if secret == "" {
+			return nil, fmt.Errorf("jwt.signing.encryption_key is required to store keys in the database")
+		}
+		return &dbKeyStore{db: db, secret: secret}, nil
+	case "file":
+		if cfg.JWT.Signing.KeyDir == "" {
+			return nil, fmt.Errorf("jwt.signing.key_dir is required to store keys on disk")
+		}
+		return &fileKeyStore{dir: cfg.JWT.Signing.KeyDir}, nil
+	default:
+		return nil, fmt.Errorf("unknown signing key storage %q", cfg.JWT.Signing.Storage)
+	}
+}
+
+// dbKeyStore keeps keys in Postgres with the private key encrypted
+type dbKeyStore struct {
+	db     *gorm.DB
+	secret string
+}
+
+func (s *dbKeyStore) Load(ctx context.Context) ([]models.SigningKey, error) {
+	var keys []models.SigningKey
+	if err := s.db.WithContext(ctx).Order("not_before").Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("failed to load signing keys: %w", err)
+	}
+
+	for i := range keys {
+		privateKey, err := utils.Decrypt(s.secret, keys[i].PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt signing key %s: %w", keys[i].ID, err)
+		}
+		keys[i].PrivateKey = privateKey
+	}
+
+	return keys, nil
+}
+
+func (s *dbKeyStore) Save(ctx context.Context, key *models.SigningKey) error {
+	privateKey, err := utils.Encrypt(s.secret, key.PrivateKey)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt signing key: %w", err)
+	}
+
+	stored := *key
+	stored.PrivateKey = privateKey
+	if err := s.db.WithContext(ctx).Create(&stored).Error; err != nil {
+		return fmt.Errorf("failed to save signing key: %w", err)
+	}
+	return nil
+}
+
+func (s *dbKeyStore) Delete(ctx context.Context, kid string) error {
+	return s.db.WithContext(ctx).Delete(&models.SigningKey{}, "id = ?", kid).Error
+}
+
+// WithLock holds a transaction scoped advisory lock while fn runs, the same
+// way the migrator serializes instances
+func (s *dbKeyStore) WithLock(ctx context.Context, fn func(store KeyStore) error) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", keyRotationLockID).Error; err != nil {
+			return fmt.Errorf("failed to lock signing keys: %w", err)
+		}
+		return fn(&dbKeyStore{db: tx, secret: s.secret})
+	})
+}
+
+// fileKeyStore keeps one JSON file per key in a directory only readable by the service
+type fileKeyStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+func (s *fileKeyStore) Load(ctx context.Context) ([]models.SigningKey, error) {
+	entries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read key directory: %w", err)
+	}
+
+	var keys []models.SigningKey
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(s.dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read signing key %s: %w", entry.Name(), err)
+		}
+
+		var key models.SigningKey
+		if err := json.Unmarshal(data, &key); err != nil {
+			return nil, fmt.Errorf("failed to parse signing key %s: %w", entry.Name(), err)
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+func (s *fileKeyStore) Save(ctx context.Context, key *models.SigningKey) error {
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create key directory: %w", err)
+	}
+
+	data, err := json.Marshal(key)
+	if err != nil {
+		return fmt.Errorf("failed to marshal signing key: %w", err)
+	}
+
+	// Write then rename so other instances never read a partial key
+	path := s.keyPath(key.ID)
+	if err := os.WriteFile(path+".tmp", data, 0o600); err != nil {
+		return fmt.Errorf("failed to write signing key: %w", err)
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func (s *fileKeyStore) Delete(ctx context.Context, kid string) error {
+	err := os.Remove(s.keyPath(kid))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// WithLock only serializes rotation within this process, instances sharing a
+// key directory should use the database store instead
+func (s *fileKeyStore) WithLock(ctx context.Context, fn func(store KeyStore) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fn(s)
+}
+
+func (s *fileKeyStore) keyPath(kid string) string {
+	return filepath.Join(s.dir, kid+".json")
+}
@@ -0,0 +1,390 @@
+package services
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/SAP-2025/auth-service/internal/config"
+	"github.com/SAP-2025/auth-service/internal/models"
+	"github.com/SAP-2025/auth-service/internal/utils"
+	"log"
+	"math/big"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported signing algorithms
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+var ErrNoSigningKey = errors.New("no active signing key")
+
+// JWK is a public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+type signingKey struct {
+	models.SigningKey
+	private crypto.Signer
+}
+
+// KeyManager owns the asymmetric keys access tokens are signed with. Keys are
+// rotated every RotationInterval; a new key is published PublishAhead before it
+// starts signing and an old key stays published until the last token it signed
+// has expired.
+type KeyManager struct {
+	store        KeyStore
+	algorithm    string
+	interval     time.Duration
+	publishAhead time.Duration
+	verifyWindow time.Duration
+
+	mu   sync.RWMutex
+	keys []*signingKey
+}
+
+func NewKeyManager(store KeyStore, cfg *config.Config) (*KeyManager, error) {
+	algorithm := cfg.JWT.Signing.Algorithm
+	switch algorithm {
+	case "":
+		algorithm = AlgorithmRS256
+	case AlgorithmRS256, AlgorithmES256, AlgorithmEdDSA:
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+
+	interval := cfg.JWT.Signing.RotationInterval
+	if interval <= 0 {
+		interval = 30 * 24 * time.Hour
+	}
+
+	publishAhead := cfg.JWT.Signing.PublishAhead
+	if publishAhead <= 0 {
+		publishAhead = time.Hour
+	}
+
+	return &KeyManager{
+		store:        store,
+		algorithm:    algorithm,
+		interval:     interval,
+		publishAhead: publishAhead,
+		verifyWindow: cfg.AccessTokenTTL(),
+	}, nil
+}
+
+// Algorithms returns the algorithms tokens may be signed with: the configured
+// one and, while the algorithm is being changed, those of keys still published
+func (m *KeyManager) Algorithms() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	algorithms := []string{m.algorithm}
+	now := time.Now()
+	for _, key := range m.keys {
+		if now.Before(key.ExpiresAt) && !slices.Contains(algorithms, key.Algorithm) {
+			algorithms = append(algorithms, key.Algorithm)
+		}
+	}
+	return algorithms
+}
+
+// Load reads the keys from the store, generating and pruning keys as the
+// rotation schedule requires. The store is locked meanwhile, so instances
+// reloading at the same time do not each generate a successor.
+func (m *KeyManager) Load(ctx context.Context) error {
+	var keys []*signingKey
+	err := m.store.WithLock(ctx, func(store KeyStore) error {
+		var err error
+		keys, err = m.rotate(ctx, store)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.keys = keys
+	m.mu.Unlock()
+
+	return nil
+}
+
+// rotate reads the stored keys and returns the live ones after generating the
+// active key or its successor when due and deleting expired keys
+func (m *KeyManager) rotate(ctx context.Context, store KeyStore) ([]*signingKey, error) {
+	keys, err := m.loadKeys(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	current := activeKey(keys, now)
+	if current == nil {
+		key, err := m.generateKey(ctx, store, now)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		current = key
+	}
+
+	if now.After(current.NotAfter.Add(-m.publishAhead)) && !hasSuccessor(keys, current) {
+		key, err := m.generateKey(ctx, store, current.NotAfter)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	live := keys[:0]
+	for _, key := range keys {
+		if now.Before(key.ExpiresAt) {
+			live = append(live, key)
+			continue
+		}
+		if err := store.Delete(ctx, key.ID); err != nil {
+			log.Printf("Failed to delete expired signing key %s: %v", key.ID, err)
+		}
+	}
+	return live, nil
+}
+
+// Run reloads and rotates the keys every minute until ctx is cancelled, so all
+// instances sharing a store converge on the same key set
+func (m *KeyManager) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.Load(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("Signing key rotation failed: %v", err)
+			}
+		}
+	}
+}
+
+// SigningKey returns the key new tokens must be signed with
+func (m *KeyManager) SigningKey() (kid string, method jwt.SigningMethod, key crypto.Signer, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	current := activeKey(m.keys, time.Now())
+	if current == nil {
+		return "", nil, nil, ErrNoSigningKey
+	}
+	return current.ID, jwt.GetSigningMethod(current.Algorithm), current.private, nil
+}
+
+// VerificationKey returns the public key and algorithm of kid
+func (m *KeyManager) VerificationKey(kid string) (crypto.PublicKey, string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, key := range m.keys {
+		if key.ID == kid {
+			return key.private.Public(), key.Algorithm, true
+		}
+	}
+	return nil, "", false
+}
+
+// JWKS returns every published public key
+func (m *KeyManager) JWKS() JWKSet {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	set := JWKSet{Keys: make([]JWK, 0, len(m.keys))}
+	for _, key := range m.keys {
+		jwk, err := toJWK(key)
+		if err != nil {
+			log.Printf("Failed to encode signing key %s: %v", key.ID, err)
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+func (m *KeyManager) loadKeys(ctx context.Context, store KeyStore) ([]*signingKey, error) {
+	stored, err := store.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]*signingKey, 0, len(stored))
+	for _, key := range stored {
+		private, err := parsePrivateKey(key.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse signing key %s: %w", key.ID, err)
+		}
+		keys = append(keys, &signingKey{SigningKey: key, private: private})
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].NotBefore.Before(keys[j].NotBefore)
+	})
+	return keys, nil
+}
+
+// generateKey creates and stores a key that starts signing at notBefore
+func (m *KeyManager) generateKey(ctx context.Context, store KeyStore, notBefore time.Time) (*signingKey, error) {
+	private, err := newPrivateKey(m.algorithm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(private.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	kid, err := utils.GenerateRandomToken(12)
+	if err != nil {
+		return nil, err
+	}
+
+	notAfter := notBefore.Add(m.interval)
+	key := &signingKey{
+		SigningKey: models.SigningKey{
+			ID:         kid,
+			Algorithm:  m.algorithm,
+			PrivateKey: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}),
+			PublicKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})),
+			NotBefore:  notBefore,
+			NotAfter:   notAfter,
+			ExpiresAt:  notAfter.Add(m.verifyWindow),
+			CreatedAt:  time.Now(),
+		},
+		private: private,
+	}
+
+	if err := store.Save(ctx, &key.SigningKey); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Generated %s signing key %s, signing from %s", m.algorithm, kid, notBefore.Format(time.RFC3339))
+	return key, nil
+}
+
+// activeKey returns the key signing at now, preferring the most recent one
+func activeKey(keys []*signingKey, now time.Time) *signingKey {
+	var active *signingKey
+	for _, key := range keys {
+		if now.Before(key.NotBefore) || !now.Before(key.NotAfter) {
+			continue
+		}
+		if active == nil || key.NotBefore.After(active.NotBefore) {
+			active = key
+		}
+	}
+	return active
+}
+
+func hasSuccessor(keys []*signingKey, current *signingKey) bool {
+	for _, key := range keys {
+		if !key.NotBefore.Before(current.NotAfter) {
+			return true
+		}
+	}
+	return false
+}
+
+func newPrivateKey(algorithm string) (crypto.Signer, error) {
+	switch algorithm {
+	case AlgorithmRS256:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case AlgorithmES256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgorithmEdDSA:
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		return private, err
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+}
+
+func parsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid PEM")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signer, nil
+}
+
+func toJWK(key *signingKey) (JWK, error) {
+	jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.Algorithm}
+	encode := base64.RawURLEncoding.EncodeToString
+
+	switch public := key.private.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encode(public.N.Bytes())
+		jwk.E = encode(big.NewInt(int64(public.E)).Bytes())
+	case *ecdsa.PublicKey:
+		point, err := public.ECDH()
+		if err != nil {
+			return JWK{}, err
+		}
+		// Uncompressed point: 0x04 || X || Y
+		raw := point.Bytes()[1:]
+		jwk.Kty = "EC"
+		jwk.Crv = "P-256"
+		jwk.X = encode(raw[:len(raw)/2])
+		jwk.Y = encode(raw[len(raw)/2:])
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = encode(public)
+	default:
+		return JWK{}, fmt.Errorf("unsupported public key type %T", public)
+	}
+
+	return jwk, nil
+}
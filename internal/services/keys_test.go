@@ -0,0 +1,248 @@
+package services
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/SAP-2025/auth-service/internal/config"
+	"github.com/SAP-2025/auth-service/internal/models"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestActiveKey(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	month := 30 * 24 * time.Hour
+	key := func(id string, notBefore time.Time) *signingKey {
+		return &signingKey{SigningKey: models.SigningKey{ID: id, NotBefore: notBefore, NotAfter: notBefore.Add(month)}}
+	}
+	// current signs for a month, next is published ahead and takes over after it
+	current, next := key("current", start), key("next", start.Add(month))
+	keys := []*signingKey{current, next}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want string
+	}{
+		{"before the first key", start.Add(-time.Second), ""},
+		{"first instant of a key", start, "current"},
+		{"successor published but not yet signing", start.Add(month - time.Second), "current"},
+		{"successor signs from the end of its predecessor", start.Add(month), "next"},
+		{"after the last key", start.Add(2 * month), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if active := activeKey(keys, tt.now); active != nil {
+				got = active.ID
+			}
+			if got != tt.want {
+				t.Errorf("activeKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Overlapping keys, for example after a clock jump, prefer the most recent one
+	overlapping := key("overlapping", start.Add(time.Hour))
+	if got := activeKey([]*signingKey{overlapping, current}, start.Add(2*time.Hour)); got != overlapping {
+		t.Errorf("activeKey() = %v, want the most recent key", got)
+	}
+
+	if hasSuccessor([]*signingKey{current}, current) {
+		t.Error("hasSuccessor() = true without a successor")
+	}
+	if !hasSuccessor(keys, current) {
+		t.Error("hasSuccessor() = false with a successor published")
+	}
+}
+
+func TestKeyManagerRotate(t *testing.T) {
+	interval := 30 * 24 * time.Hour
+
+	tests := []struct {
+		name string
+		// NotBefore of the stored keys relative to now
+		stored []time.Duration
+		// index of the active key after rotation, -1 for a newly generated key
+		wantActive    int
+		wantLive      int
+		wantSuccessor bool
+	}{
+		{
+			name:       "empty store generates an active key",
+			wantActive: -1,
+			wantLive:   1,
+		},
+		{
+			name:       "current key far from its end",
+			stored:     []time.Duration{-24 * time.Hour},
+			wantActive: 0,
+			wantLive:   1,
+		},
+		{
+			name:          "successor published ahead",
+			stored:        []time.Duration{-interval + 30*time.Minute},
+			wantActive:    0,
+			wantLive:      2,
+			wantSuccessor: true,
+		},
+		{
+			name:          "successor not generated twice",
+			stored:        []time.Duration{-interval + 30*time.Minute, 30 * time.Minute},
+			wantActive:    0,
+			wantLive:      2,
+			wantSuccessor: true,
+		},
+		{
+			name:       "retired key stays published until its tokens expire",
+			stored:     []time.Duration{-interval - 5*time.Minute, -5 * time.Minute},
+			wantActive: 1,
+			wantLive:   2,
+		},
+		{
+			name:       "expired key is deleted",
+			stored:     []time.Duration{-2 * interval, -24 * time.Hour},
+			wantActive: 1,
+			wantLive:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			cfg := &config.Config{}
+			cfg.JWT.Signing.Algorithm = AlgorithmES256
+			cfg.JWT.Signing.RotationInterval = interval
+			store := &fileKeyStore{dir: t.TempDir()}
+			m, err := NewKeyManager(store, cfg)
+			if err != nil {
+				t.Fatalf("NewKeyManager() error = %v", err)
+			}
+
+			now := time.Now()
+			var stored []*signingKey
+			for _, offset := range tt.stored {
+				key, err := m.generateKey(ctx, store, now.Add(offset))
+				if err != nil {
+					t.Fatalf("generateKey() error = %v", err)
+				}
+				stored = append(stored, key)
+			}
+
+			live, err := m.rotate(ctx, store)
+			if err != nil {
+				t.Fatalf("rotate() error = %v", err)
+			}
+			if len(live) != tt.wantLive {
+				t.Fatalf("rotate() returned %d keys, want %d", len(live), tt.wantLive)
+			}
+			if remaining, _ := store.Load(ctx); len(remaining) != tt.wantLive {
+				t.Errorf("store holds %d keys, want %d", len(remaining), tt.wantLive)
+			}
+
+			active := activeKey(live, time.Now())
+			switch {
+			case active == nil:
+				t.Fatal("no active key after rotation")
+			case tt.wantActive >= 0 && active.ID != stored[tt.wantActive].ID:
+				t.Errorf("active key = %s, want stored key %d", active.ID, tt.wantActive)
+			case tt.wantActive < 0 && len(stored) > 0:
+				t.Errorf("active key = %s, want a new key", active.ID)
+			}
+
+			if got := hasSuccessor(live, active); got != tt.wantSuccessor {
+				t.Errorf("successor published = %v, want %v", got, tt.wantSuccessor)
+			}
+			for _, key := range live {
+				if key != active && key.NotBefore.After(active.NotBefore) && !key.NotBefore.Equal(active.NotAfter) {
+					t.Errorf("successor signs from %s, want %s", key.NotBefore, active.NotAfter)
+				}
+			}
+		})
+	}
+}
+
+func TestToJWKRoundTrip(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		kty       string
+		crv       string
+	}{
+		{AlgorithmRS256, "RSA", ""},
+		{AlgorithmES256, "EC", "P-256"},
+		{AlgorithmEdDSA, "OKP", "Ed25519"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			private, err := newPrivateKey(tt.algorithm)
+			if err != nil {
+				t.Fatalf("newPrivateKey() error = %v", err)
+			}
+			key := &signingKey{SigningKey: models.SigningKey{ID: "kid-1", Algorithm: tt.algorithm}, private: private}
+
+			jwk, err := toJWK(key)
+			if err != nil {
+				t.Fatalf("toJWK() error = %v", err)
+			}
+			if jwk.Kty != tt.kty || jwk.Crv != tt.crv || jwk.Alg != tt.algorithm || jwk.Kid != "kid-1" || jwk.Use != "sig" {
+				t.Errorf("toJWK() = %+v", jwk)
+			}
+
+			// Verify with the key as a verifier reads it from the JWKS document
+			data, err := json.Marshal(JWKSet{Keys: []JWK{jwk}})
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			var set JWKSet
+			if err := json.Unmarshal(data, &set); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			public := publicKeyFromJWK(t, set.Keys[0])
+
+			signed, err := jwt.NewWithClaims(jwt.GetSigningMethod(tt.algorithm), jwt.MapClaims{"sub": "1"}).SignedString(private)
+			if err != nil {
+				t.Fatalf("SignedString() error = %v", err)
+			}
+			_, err = jwt.Parse(signed, func(*jwt.Token) (interface{}, error) {
+				return public, nil
+			}, jwt.WithValidMethods([]string{tt.algorithm}))
+			if err != nil {
+				t.Errorf("signature does not verify with the published key: %v", err)
+			}
+		})
+	}
+}
+
+// publicKeyFromJWK decodes a JWK the way RFC 7518 and RFC 8037 define it
+func publicKeyFromJWK(t *testing.T, jwk JWK) crypto.PublicKey {
+	t.Helper()
+	decode := func(value string) []byte {
+		data, err := base64.RawURLEncoding.DecodeString(value)
+		if err != nil {
+			t.Fatalf("invalid base64url %q: %v", value, err)
+		}
+		return data
+	}
+
+	switch jwk.Kty {
+	case "RSA":
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(decode(jwk.N)), E: int(new(big.Int).SetBytes(decode(jwk.E)).Int64())}
+	case "EC":
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(decode(jwk.X)), Y: new(big.Int).SetBytes(decode(jwk.Y))}
+	case "OKP":
+		return ed25519.PublicKey(decode(jwk.X))
+	}
+	t.Fatalf("unsupported key type %q", jwk.Kty)
+	return nil
+}
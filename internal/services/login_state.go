@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/SAP-2025/auth-service/internal/config"
+	"github.com/SAP-2025/auth-service/internal/utils"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrLoginStateNotFound = errors.New("login state not found or expired")
+
+// LoginState is kept between the login redirect and the callback
+type LoginState struct {
+	utils.PKCEChallenge
+	// Expected nonce claim of the ID token
+	Nonce string `json:",omitempty"`
+	// Frontend URL the browser is sent to after the callback, empty for JSON responses
+	ReturnTo string `json:",omitempty"`
+}
+
+// LoginStateStore keeps pending logins keyed by their OAuth state parameter
+type LoginStateStore interface {
+	Save(ctx context.Context, state string, loginState *LoginState) error
+	// Consume returns and deletes the login state atomically, so a state can
+	// complete only one callback
+	Consume(ctx context.Context, state string) (*LoginState, error)
+	Exists(ctx context.Context, state string) (bool, error)
+	Delete(ctx context.Context, state string) error
+}
+
+// NewLoginStateStore returns the store selected by Session.LoginStateStore
+func NewLoginStateStore(client *redis.Client, cfg *config.Config) (LoginStateStore, error) {
+	switch cfg.Session.LoginStateStore {
+	case "", "redis":
+		return &redisLoginStateStore{client: client, ttl: cfg.LoginStateTTL()}, nil
+	case "memory":
+		// Only suitable for a single instance, e.g. local development
+		return &memoryLoginStateStore{ttl: cfg.LoginStateTTL(), states: make(map[string]memoryLoginState)}, nil
+	default:
+		return nil, fmt.Errorf("unknown login state store %q", cfg.Session.LoginStateStore)
+	}
+}
+
+type redisLoginStateStore struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func (s *redisLoginStateStore) Save(ctx context.Context, state string, loginState *LoginState) error {
+	data, err := json.Marshal(loginState)
+	if err != nil {
+		return fmt.Errorf("failed to marshal login state: %w", err)
+	}
+
+	if err := s.client.Set(ctx, s.getStateKey(state), data, s.ttl).Err(); err != nil {
+		return fmt.Errorf("failed to save login state: %w", err)
+	}
+	return nil
+}
+
+func (s *redisLoginStateStore) Consume(ctx context.Context, state string) (*LoginState, error) {
+	data, err := s.client.GetDel(ctx, s.getStateKey(state)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrLoginStateNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get login state: %w", err)
+	}
+
+	var loginState LoginState
+	if err := json.Unmarshal([]byte(data), &loginState); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal login state: %w", err)
+	}
+	return &loginState, nil
+}
+
+func (s *redisLoginStateStore) Exists(ctx context.Context, state string) (bool, error) {
+	exists, err := s.client.Exists(ctx, s.getStateKey(state)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check login state: %w", err)
+	}
+	return exists > 0, nil
+}
+
+func (s *redisLoginStateStore) Delete(ctx context.Context, state string) error {
+	return s.client.Del(ctx, s.getStateKey(state)).Err()
+}
+
+// Helper function to generate Redis key
+func (s *redisLoginStateStore) getStateKey(state string) string {
+	return fmt.Sprintf("pkce:session:%s", state)
+}
+
+type memoryLoginState struct {
+	loginState LoginState
+	expiresAt  time.Time
+}
+
+// memoryLoginStateStore keeps login states in process memory
+type memoryLoginStateStore struct {
+	ttl time.Duration
+
+	mu     sync.Mutex
+	states map[string]memoryLoginState
+}
+
+func (s *memoryLoginStateStore) Save(ctx context.Context, state string, loginState *LoginState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, entry := range s.states {
+		if now.After(entry.expiresAt) {
+			delete(s.states, key)
+		}
+	}
+
+	s.states[state] = memoryLoginState{loginState: *loginState, expiresAt: now.Add(s.ttl)}
+	return nil
+}
+
+func (s *memoryLoginStateStore) Consume(ctx context.Context, state string) (*LoginState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.states[state]
+	if !ok {
+		return nil, ErrLoginStateNotFound
+	}
+	delete(s.states, state)
+
+	if time.Now().After(entry.expiresAt) {
+		return nil, ErrLoginStateNotFound
+	}
+	return &entry.loginState, nil
+}
+
+func (s *memoryLoginStateStore) Exists(ctx context.Context, state string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.states[state]
+	return ok && time.Now().Before(entry.expiresAt), nil
+}
+
+func (s *memoryLoginStateStore) Delete(ctx context.Context, state string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.states, state)
+	return nil
+}
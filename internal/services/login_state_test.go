@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/SAP-2025/auth-service/internal/config"
+	"github.com/SAP-2025/auth-service/internal/utils"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestMemoryLoginStateStore(t *testing.T, ttl time.Duration) LoginStateStore {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Session.LoginStateStore = "memory"
+	cfg.Session.LoginStateTTL = ttl
+
+	store, err := NewLoginStateStore(nil, cfg)
+	if err != nil {
+		t.Fatalf("NewLoginStateStore() error = %v", err)
+	}
+	return store
+}
+
+func TestMemoryLoginStateStoreConsumeOnce(t *testing.T) {
+	ctx := context.Background()
+	store := newTestMemoryLoginStateStore(t, time.Minute)
+	saved := &LoginState{PKCEChallenge: utils.PKCEChallenge{CodeVerifier: "verifier"}, Nonce: "nonce-1"}
+
+	if err := store.Save(ctx, "state-1", saved); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	got, err := store.Consume(ctx, "state-1")
+	if err != nil {
+		t.Fatalf("Consume() error = %v", err)
+	}
+	if got.CodeVerifier != "verifier" || got.Nonce != "nonce-1" {
+		t.Errorf("Consume() = %+v, want the saved state", got)
+	}
+
+	if _, err := store.Consume(ctx, "state-1"); !errors.Is(err, ErrLoginStateNotFound) {
+		t.Errorf("second Consume() error = %v, want %v", err, ErrLoginStateNotFound)
+	}
+	if exists, _ := store.Exists(ctx, "state-1"); exists {
+		t.Error("Exists() = true after Consume()")
+	}
+}
+
+func TestMemoryLoginStateStoreConcurrentConsume(t *testing.T) {
+	ctx := context.Background()
+	store := newTestMemoryLoginStateStore(t, time.Minute)
+	if err := store.Save(ctx, "state-1", &LoginState{Nonce: "nonce-1"}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// Replayed callbacks racing each other, exactly one may complete
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	consumed := 0
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := store.Consume(ctx, "state-1"); err == nil {
+				mu.Lock()
+				consumed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if consumed != 1 {
+		t.Errorf("%d concurrent Consume() calls succeeded, want 1", consumed)
+	}
+}
+
+func TestMemoryLoginStateStoreExpiry(t *testing.T) {
+	ctx := context.Background()
+	ttl := 50 * time.Millisecond
+	store := newTestMemoryLoginStateStore(t, ttl)
+
+	if err := store.Save(ctx, "state-1", &LoginState{Nonce: "nonce-1"}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if exists, _ := store.Exists(ctx, "state-1"); !exists {
+		t.Fatal("Exists() = false before the TTL passed")
+	}
+
+	time.Sleep(2 * ttl)
+
+	if exists, _ := store.Exists(ctx, "state-1"); exists {
+		t.Error("Exists() = true after the TTL passed")
+	}
+	if _, err := store.Consume(ctx, "state-1"); !errors.Is(err, ErrLoginStateNotFound) {
+		t.Errorf("Consume() error = %v, want %v", err, ErrLoginStateNotFound)
+	}
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"github.com/SAP-2025/auth-service/internal/config"
+	"slices"
+)
+
+// Permissions checked by RequireAnyPermission
+const (
+	PermissionUsersRead       = "users:read"
+	PermissionUsersWrite      = "users:write"
+	PermissionSessionsRevoke  = "sessions:revoke"
+	PermissionTokensRevokeAll = "tokens:revoke_all"
+)
+
+var defaultRolePermissions = map[string][]string{
+	"admin": {PermissionUsersRead, PermissionUsersWrite, PermissionSessionsRevoke, PermissionTokensRevokeAll},
+}
+
+// RolePermissions resolves the permissions granted to local roles
+type RolePermissions struct {
+	permissions map[string][]string
+}
+
+func NewRolePermissions(cfg *config.Config) *RolePermissions {
+	permissions := make(map[string][]string, len(localRoles))
+	for role, granted := range defaultRolePermissions {
+		permissions[role] = granted
+	}
+	for role, granted := range cfg.RoleMapping.Permissions {
+		permissions[role] = granted
+	}
+	return &RolePermissions{permissions: permissions}
+}
+
+// Has reports whether role is granted permission
+func (p *RolePermissions) Has(role, permission string) bool {
+	return slices.Contains(p.permissions[role], permission)
+}
+
+// For returns the permissions granted to role
+func (p *RolePermissions) For(role string) []string {
+	return p.permissions[role]
+}
@@ -0,0 +1,263 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Policy effects
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+)
+
+// Condition operators
+const (
+	OperatorEquals    = "eq"
+	OperatorNotEquals = "ne"
+	OperatorIn        = "in"
+	OperatorNotIn     = "not_in"
+)
+
+var ErrInvalidPolicy = errors.New("invalid policy")
+
+// PolicyDocument is a versioned set of policies, read from YAML or JSON
+type PolicyDocument struct {
+	Version  int      `yaml:"version" json:"version"`
+	Policies []Policy `yaml:"policies" json:"policies"`
+
+	// hash of the source the document was parsed from
+	hash string
+}
+
+// Policy grants or denies actions on resource types to subjects holding one
+// of Roles when every condition holds
+type Policy struct {
+	ID          string            `yaml:"id" json:"id"`
+	Description string            `yaml:"description" json:"description,omitempty"`
+	Effect      string            `yaml:"effect" json:"effect"`
+	Roles       []string          `yaml:"roles" json:"roles,omitempty"`           // any role when empty
+	Actions     []string          `yaml:"actions" json:"actions"`                 // "*" matches every action
+	Resources   []string          `yaml:"resources" json:"resources"`             // resource types, "*" matches every type
+	Conditions  []PolicyCondition `yaml:"conditions" json:"conditions,omitempty"` // all must hold
+}
+
+// PolicyCondition compares an attribute such as resource.organization with a
+// literal Value, a list of Values or the attribute named by ValueFrom
+type PolicyCondition struct {
+	Attribute string   `yaml:"attribute" json:"attribute"`
+	Operator  string   `yaml:"operator" json:"operator"`
+	Value     string   `yaml:"value" json:"value,omitempty"`
+	Values    []string `yaml:"values" json:"values,omitempty"`
+	ValueFrom string   `yaml:"value_from" json:"value_from,omitempty"`
+}
+
+// PolicySubject is the user an authorization check is made for
+type PolicySubject struct {
+	ID           uint
+	Username     string
+	Role         string
+	Organization string
+}
+
+// PolicyResource is the object an authorization check is made on
+type PolicyResource struct {
+	Type         string            `json:"type"`
+	ID           string            `json:"id,omitempty"`
+	OwnerID      string            `json:"owner_id,omitempty"`
+	Organization string            `json:"organization,omitempty"`
+	Attributes   map[string]string `json:"attributes,omitempty"`
+}
+
+// PolicyDecision is the outcome of an authorization check
+type PolicyDecision struct {
+	Allowed       bool   `json:"allowed"`
+	PolicyID      string `json:"policy_id,omitempty"`
+	PolicyVersion int    `json:"policy_version"`
+	Reason        string `json:"reason"`
+}
+
+// ParsePolicyDocument reads and validates a YAML or JSON policy document
+func ParsePolicyDocument(data []byte) (*PolicyDocument, error) {
+	var document PolicyDocument
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPolicy, err)
+	}
+	if err := document.Validate(); err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	document.hash = hex.EncodeToString(sum[:])
+	return &document, nil
+}
+
+// Validate rejects documents the engine could misread
+func (d *PolicyDocument) Validate() error {
+	if d.Version <= 0 {
+		return fmt.Errorf("%w: version must be positive", ErrInvalidPolicy)
+	}
+
+	ids := make(map[string]bool, len(d.Policies))
+	for i, policy := range d.Policies {
+		if policy.ID == "" {
+			return fmt.Errorf("%w: policy %d has no id", ErrInvalidPolicy, i)
+		}
+		if ids[policy.ID] {
+			return fmt.Errorf("%w: duplicate policy id %q", ErrInvalidPolicy, policy.ID)
+		}
+		ids[policy.ID] = true
+
+		if policy.Effect != EffectAllow && policy.Effect != EffectDeny {
+			return fmt.Errorf("%w: policy %q: effect must be allow or deny", ErrInvalidPolicy, policy.ID)
+		}
+		if len(policy.Actions) == 0 || len(policy.Resources) == 0 {
+			return fmt.Errorf("%w: policy %q: actions and resources are required", ErrInvalidPolicy, policy.ID)
+		}
+		for _, condition := range policy.Conditions {
+			if err := condition.validate(); err != nil {
+				return fmt.Errorf("%w: policy %q: %v", ErrInvalidPolicy, policy.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (c PolicyCondition) validate() error {
+	if !isPolicyAttribute(c.Attribute) {
+		return fmt.Errorf("unknown attribute %q", c.Attribute)
+	}
+	if c.ValueFrom != "" && !isPolicyAttribute(c.ValueFrom) {
+		return fmt.Errorf("unknown attribute %q", c.ValueFrom)
+	}
+
+	switch c.Operator {
+	case OperatorEquals, OperatorNotEquals:
+		if len(c.Values) > 0 {
+			return fmt.Errorf("operator %s takes value or value_from", c.Operator)
+		}
+	case OperatorIn, OperatorNotIn:
+		if c.ValueFrom != "" || c.Value != "" {
+			return fmt.Errorf("operator %s takes values", c.Operator)
+		}
+	default:
+		return fmt.Errorf("unknown operator %q", c.Operator)
+	}
+	return nil
+}
+
+func isPolicyAttribute(name string) bool {
+	switch name {
+	case "subject.id", "subject.username", "subject.role", "subject.organization",
+		"resource.type", "resource.id", "resource.owner_id", "resource.organization":
+		return true
+	}
+	key, ok := strings.CutPrefix(name, "resource.attributes.")
+	return ok && key != ""
+}
+
+// Evaluate decides whether subject may perform action on resource. Deny
+// policies override allow policies and nothing is allowed by default.
+func (d *PolicyDocument) Evaluate(subject *PolicySubject, action string, resource *PolicyResource) PolicyDecision {
+	var allowedBy *Policy
+	for i := range d.Policies {
+		policy := &d.Policies[i]
+		if !policy.matches(subject, action, resource) {
+			continue
+		}
+
+		if policy.Effect == EffectDeny {
+			return PolicyDecision{
+				PolicyID:      policy.ID,
+				PolicyVersion: d.Version,
+				Reason:        "denied by policy " + policy.ID,
+			}
+		}
+		if allowedBy == nil {
+			allowedBy = policy
+		}
+	}
+
+	if allowedBy == nil {
+		return PolicyDecision{PolicyVersion: d.Version, Reason: "no policy allows the action"}
+	}
+	return PolicyDecision{
+		Allowed:       true,
+		PolicyID:      allowedBy.ID,
+		PolicyVersion: d.Version,
+		Reason:        "allowed by policy " + allowedBy.ID,
+	}
+}
+
+func (p *Policy) matches(subject *PolicySubject, action string, resource *PolicyResource) bool {
+	if len(p.Roles) > 0 && !slices.Contains(p.Roles, subject.Role) {
+		return false
+	}
+	if !matchesAny(p.Actions, action) || !matchesAny(p.Resources, resource.Type) {
+		return false
+	}
+
+	for _, condition := range p.Conditions {
+		if !condition.holds(subject, resource) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesAny(patterns []string, value string) bool {
+	return slices.Contains(patterns, "*") || slices.Contains(patterns, value)
+}
+
+func (c PolicyCondition) holds(subject *PolicySubject, resource *PolicyResource) bool {
+	actual := policyAttribute(c.Attribute, subject, resource)
+
+	switch c.Operator {
+	case OperatorEquals, OperatorNotEquals:
+		expected := c.Value
+		if c.ValueFrom != "" {
+			expected = policyAttribute(c.ValueFrom, subject, resource)
+			// Two missing attributes are not equal, an unowned resource must
+			// not match a subject without organization
+			if expected == "" {
+				return c.Operator == OperatorNotEquals
+			}
+		}
+		return (actual == expected) == (c.Operator == OperatorEquals)
+	case OperatorIn:
+		return slices.Contains(c.Values, actual)
+	case OperatorNotIn:
+		return !slices.Contains(c.Values, actual)
+	}
+	return false
+}
+
+func policyAttribute(name string, subject *PolicySubject, resource *PolicyResource) string {
+	switch name {
+	case "subject.id":
+		return strconv.FormatUint(uint64(subject.ID), 10)
+	case "subject.username":
+		return subject.Username
+	case "subject.role":
+		return subject.Role
+	case "subject.organization":
+		return subject.Organization
+	case "resource.type":
+		return resource.Type
+	case "resource.id":
+		return resource.ID
+	case "resource.owner_id":
+		return resource.OwnerID
+	case "resource.organization":
+		return resource.Organization
+	}
+	key, _ := strings.CutPrefix(name, "resource.attributes.")
+	return resource.Attributes[key]
+}
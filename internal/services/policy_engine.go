@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/SAP-2025/auth-service/internal/config"
+	"github.com/SAP-2025/auth-service/internal/db"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// PolicySource loads the current policy document
+type PolicySource interface {
+	Load(ctx context.Context) (*PolicyDocument, error)
+}
+
+// NewPolicySource returns the policy source selected by Authorization.PolicySource
+func NewPolicySource(policies db.PolicyRepository, cfg *config.Config) (PolicySource, error) {
+	switch cfg.Authorization.PolicySource {
+	case "", "database":
+		return &dbPolicySource{policies: policies}, nil
+	case "file":
+		if cfg.Authorization.PolicyFile == "" {
+			return nil, fmt.Errorf("authorization.policy_file is required to load policies from a file")
+		}
+		return &filePolicySource{path: cfg.Authorization.PolicyFile}, nil
+	default:
+		return nil, fmt.Errorf("unknown policy source %q", cfg.Authorization.PolicySource)
+	}
+}
+
+// filePolicySource reads a YAML or JSON policy document from disk
+type filePolicySource struct {
+	path string
+}
+
+func (s *filePolicySource) Load(ctx context.Context) (*PolicyDocument, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+	return ParsePolicyDocument(data)
+}
+
+// dbPolicySource reads the highest policy set version from Postgres
+type dbPolicySource struct {
+	policies db.PolicyRepository
+}
+
+func (s *dbPolicySource) Load(ctx context.Context) (*PolicyDocument, error) {
+	set, err := s.policies.Latest(ctx)
+	if errors.Is(err, db.ErrNotFound) {
+		return &PolicyDocument{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load policies: %w", err)
+	}
+
+	document, err := ParsePolicyDocument([]byte(set.Document))
+	if err != nil {
+		return nil, fmt.Errorf("policy set %d: %w", set.Version, err)
+	}
+	if document.Version != set.Version {
+		return nil, fmt.Errorf("policy set %d holds document version %d", set.Version, document.Version)
+	}
+	return document, nil
+}
+
+// PolicyEngine evaluates authorization checks against the active policy
+// document and reloads it whenever its content changes
+type PolicyEngine struct {
+	source   PolicySource
+	interval time.Duration
+
+	mu       sync.RWMutex
+	document *PolicyDocument
+}
+
+func NewPolicyEngine(source PolicySource, cfg *config.Config) *PolicyEngine {
+	interval := cfg.Authorization.ReloadInterval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	return &PolicyEngine{
+		source:   source,
+		interval: interval,
+		document: &PolicyDocument{},
+	}
+}
+
+// Load activates the document of the source if its content differs from the
+// active one, edits that keep the version are picked up too. A document
+// failing to load leaves the active one in place.
+func (e *PolicyEngine) Load(ctx context.Context) error {
+	document, err := e.source.Load(ctx)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if document.hash == e.document.hash {
+		return nil
+	}
+
+	if document.Version < e.document.Version {
+		log.Printf("Policy version went back from %d to %d", e.document.Version, document.Version)
+	} else if document.Version == e.document.Version {
+		log.Printf("Policy version %d changed without a version bump", document.Version)
+	}
+	if len(document.Policies) == 0 {
+		log.Printf("Policy version %d has no policies, every authorization check is denied", document.Version)
+	} else {
+		log.Printf("Loaded policy version %d with %d policies", document.Version, len(document.Policies))
+	}
+	e.document = document
+	return nil
+}
+
+// Run reloads the policies until ctx is cancelled
+func (e *PolicyEngine) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := e.Load(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("Policy reload failed: %v", err)
+			}
+		}
+	}
+}
+
+// Evaluate decides a check against the active policies
+func (e *PolicyEngine) Evaluate(subject *PolicySubject, action string, resource *PolicyResource) PolicyDecision {
+	e.mu.RLock()
+	document := e.document
+	e.mu.RUnlock()
+
+	return document.Evaluate(subject, action, resource)
+}
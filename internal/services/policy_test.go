@@ -0,0 +1,311 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/SAP-2025/auth-service/internal/config"
+	"testing"
+)
+
+func TestPolicyDocumentEvaluate(t *testing.T) {
+	document, err := ParsePolicyDocument([]byte(`
+version: 3
+policies:
+  - id: teachers-manage-own-org-exams
+    effect: allow
+    roles: [teacher]
+    actions: [read, update]
+    resources: [exam]
+    conditions:
+      - attribute: resource.organization
+        operator: eq
+        value_from: subject.organization
+  - id: owners-read-submissions
+    effect: allow
+    actions: [read]
+    resources: [submission]
+    conditions:
+      - attribute: resource.owner_id
+        operator: eq
+        value_from: subject.id
+  - id: admins-everything
+    effect: allow
+    roles: [admin]
+    actions: ["*"]
+    resources: ["*"]
+  - id: no-changes-to-archived
+    effect: deny
+    actions: [update, delete]
+    resources: ["*"]
+    conditions:
+      - attribute: resource.attributes.status
+        operator: in
+        values: [archived, locked]
+  - id: proctors-read-open-exams
+    effect: allow
+    roles: [proctor]
+    actions: [read]
+    resources: [exam]
+    conditions:
+      - attribute: resource.attributes.status
+        operator: not_in
+        values: [draft]
+      - attribute: subject.username
+        operator: ne
+        value: banned-proctor
+`))
+	if err != nil {
+		t.Fatalf("ParsePolicyDocument() error = %v", err)
+	}
+
+	teacher := &PolicySubject{ID: 7, Username: "alice", Role: "teacher", Organization: "uni"}
+	orphanTeacher := &PolicySubject{ID: 8, Username: "bob", Role: "teacher"}
+	student := &PolicySubject{ID: 9, Username: "carol", Role: "student", Organization: "uni"}
+	admin := &PolicySubject{ID: 1, Username: "root", Role: "admin"}
+	proctor := &PolicySubject{ID: 5, Username: "dave", Role: "proctor"}
+	bannedProctor := &PolicySubject{ID: 6, Username: "banned-proctor", Role: "proctor"}
+
+	tests := []struct {
+		name     string
+		subject  *PolicySubject
+		action   string
+		resource PolicyResource
+		allowed  bool
+		policyID string
+	}{
+		{
+			name:     "allowed by matching role and condition",
+			subject:  teacher,
+			action:   "update",
+			resource: PolicyResource{Type: "exam", Organization: "uni"},
+			allowed:  true,
+			policyID: "teachers-manage-own-org-exams",
+		},
+		{
+			name:     "eq value_from fails for another organization",
+			subject:  teacher,
+			action:   "read",
+			resource: PolicyResource{Type: "exam", Organization: "other"},
+		},
+		{
+			name:     "two missing attributes are not equal",
+			subject:  orphanTeacher,
+			action:   "read",
+			resource: PolicyResource{Type: "exam"},
+		},
+		{
+			name:     "role not listed",
+			subject:  student,
+			action:   "read",
+			resource: PolicyResource{Type: "exam", Organization: "uni"},
+		},
+		{
+			name:     "action not listed",
+			subject:  teacher,
+			action:   "delete",
+			resource: PolicyResource{Type: "exam", Organization: "uni"},
+		},
+		{
+			name:     "policy without roles applies to any role",
+			subject:  student,
+			action:   "read",
+			resource: PolicyResource{Type: "submission", OwnerID: "9"},
+			allowed:  true,
+			policyID: "owners-read-submissions",
+		},
+		{
+			name:     "subject id compared as string",
+			subject:  student,
+			action:   "read",
+			resource: PolicyResource{Type: "submission", OwnerID: "10"},
+		},
+		{
+			name:     "wildcards",
+			subject:  admin,
+			action:   "delete",
+			resource: PolicyResource{Type: "course"},
+			allowed:  true,
+			policyID: "admins-everything",
+		},
+		{
+			name:     "deny overrides an earlier allow",
+			subject:  admin,
+			action:   "delete",
+			resource: PolicyResource{Type: "course", Attributes: map[string]string{"status": "archived"}},
+			policyID: "no-changes-to-archived",
+		},
+		{
+			name:     "deny overrides every matching allow",
+			subject:  teacher,
+			action:   "update",
+			resource: PolicyResource{Type: "exam", Organization: "uni", Attributes: map[string]string{"status": "locked"}},
+			policyID: "no-changes-to-archived",
+		},
+		{
+			name:     "deny only applies to its actions",
+			subject:  teacher,
+			action:   "read",
+			resource: PolicyResource{Type: "exam", Organization: "uni", Attributes: map[string]string{"status": "archived"}},
+			allowed:  true,
+			policyID: "teachers-manage-own-org-exams",
+		},
+		{
+			name:     "not_in holds for a missing attribute",
+			subject:  proctor,
+			action:   "read",
+			resource: PolicyResource{Type: "exam"},
+			allowed:  true,
+			policyID: "proctors-read-open-exams",
+		},
+		{
+			name:     "not_in fails for a listed value",
+			subject:  proctor,
+			action:   "read",
+			resource: PolicyResource{Type: "exam", Attributes: map[string]string{"status": "draft"}},
+		},
+		{
+			name:     "ne fails for the same value",
+			subject:  bannedProctor,
+			action:   "read",
+			resource: PolicyResource{Type: "exam"},
+		},
+		{
+			name:     "nothing is allowed by default",
+			subject:  student,
+			action:   "read",
+			resource: PolicyResource{Type: "course"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource := tt.resource
+			decision := document.Evaluate(tt.subject, tt.action, &resource)
+			if decision.Allowed != tt.allowed || decision.PolicyID != tt.policyID {
+				t.Errorf("Evaluate() = allowed %v by %q, want allowed %v by %q (%s)",
+					decision.Allowed, decision.PolicyID, tt.allowed, tt.policyID, decision.Reason)
+			}
+			if decision.PolicyVersion != 3 {
+				t.Errorf("Evaluate() policy version = %d, want 3", decision.PolicyVersion)
+			}
+		})
+	}
+}
+
+func TestParsePolicyDocumentRejectsInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+	}{
+		{"malformed", `version: [`},
+		{"missing version", `policies: []`},
+		{"policy without id", `
+version: 1
+policies:
+  - effect: allow
+    actions: [read]
+    resources: [exam]`},
+		{"duplicate id", `
+version: 1
+policies:
+  - {id: a, effect: allow, actions: [read], resources: [exam]}
+  - {id: a, effect: deny, actions: [read], resources: [exam]}`},
+		{"unknown effect", `
+version: 1
+policies:
+  - {id: a, effect: permit, actions: [read], resources: [exam]}`},
+		{"missing actions", `
+version: 1
+policies:
+  - {id: a, effect: allow, resources: [exam]}`},
+		{"unknown attribute", `
+version: 1
+policies:
+  - id: a
+    effect: allow
+    actions: [read]
+    resources: [exam]
+    conditions:
+      - {attribute: subject.email, operator: eq, value: x}`},
+		{"unknown value_from attribute", `
+version: 1
+policies:
+  - id: a
+    effect: allow
+    actions: [read]
+    resources: [exam]
+    conditions:
+      - {attribute: resource.owner_id, operator: eq, value_from: subject.email}`},
+		{"unknown operator", `
+version: 1
+policies:
+  - id: a
+    effect: allow
+    actions: [read]
+    resources: [exam]
+    conditions:
+      - {attribute: subject.role, operator: gt, value: x}`},
+		{"eq with values", `
+version: 1
+policies:
+  - id: a
+    effect: allow
+    actions: [read]
+    resources: [exam]
+    conditions:
+      - {attribute: subject.role, operator: eq, values: [x]}`},
+		{"in with value", `
+version: 1
+policies:
+  - id: a
+    effect: allow
+    actions: [read]
+    resources: [exam]
+    conditions:
+      - {attribute: subject.role, operator: in, value: x}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParsePolicyDocument([]byte(tt.document)); !errors.Is(err, ErrInvalidPolicy) {
+				t.Errorf("ParsePolicyDocument() error = %v, want %v", err, ErrInvalidPolicy)
+			}
+		})
+	}
+}
+
+type staticPolicySource struct {
+	data string
+}
+
+func (s *staticPolicySource) Load(ctx context.Context) (*PolicyDocument, error) {
+	return ParsePolicyDocument([]byte(s.data))
+}
+
+func TestPolicyEngineReloadsEditsWithoutVersionBump(t *testing.T) {
+	source := &staticPolicySource{data: `
+version: 1
+policies:
+  - {id: read-exams, effect: allow, actions: [read], resources: [exam]}`}
+	engine := NewPolicyEngine(source, &config.Config{})
+	subject := &PolicySubject{ID: 1, Role: "student"}
+	resource := &PolicyResource{Type: "exam"}
+
+	if err := engine.Load(context.Background()); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !engine.Evaluate(subject, "read", resource).Allowed {
+		t.Fatal("Evaluate() denied before the edit, want allowed")
+	}
+
+	source.data = `
+version: 1
+policies:
+  - {id: read-exams, effect: deny, actions: [read], resources: [exam]}`
+	if err := engine.Load(context.Background()); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if engine.Evaluate(subject, "read", resource).Allowed {
+		t.Error("Evaluate() allowed after the edit, want the edited policy to apply")
+	}
+}
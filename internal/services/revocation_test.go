@@ -0,0 +1,31 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEpochAt(t *testing.T) {
+	bump := time.Date(2026, 3, 1, 12, 0, 10, 700*int(time.Millisecond), time.UTC)
+
+	tests := []struct {
+		name     string
+		issuedAt time.Time
+		revoked  bool
+	}{
+		{"iat of the second before the bump", bump.Add(-time.Second).Truncate(time.Second), true},
+		{"iat of the second of the bump", bump.Truncate(time.Second), false},
+		{"iat after the bump", bump.Add(time.Second).Truncate(time.Second), false},
+		{"session used just before the bump", bump.Add(-time.Millisecond), true},
+		{"session created just after the bump", bump.Add(time.Millisecond), false},
+		{"session created at the bump", bump, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.issuedAt.Before(epochAt(bump, tt.issuedAt)); got != tt.revoked {
+				t.Errorf("issued at %s revoked = %v, want %v", tt.issuedAt.Format(time.RFC3339Nano), got, tt.revoked)
+			}
+		})
+	}
+}
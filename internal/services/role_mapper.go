@@ -0,0 +1,148 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"github.com/SAP-2025/auth-service/internal/config"
+	"slices"
+	"strings"
+
+	"github.com/casdoor/casdoor-go-sdk/casdoorsdk"
+)
+
+const defaultRole = "student"
+
+// ErrRoleUnmapped rejects users no role mapping rule matches when unmapped users are denied
+var ErrRoleUnmapped = errors.New("no local role is mapped to the user")
+
+// Local roles allowed by the users.role check constraint
+var localRoles = []string{"admin", "proctor", "teacher", "student"}
+
+// RoleMapper derives the local role of a user from their Casdoor claims
+type RoleMapper struct {
+	rules        []config.RoleRule
+	defaultRole  string
+	denyUnmapped bool
+}
+
+func NewRoleMapper(cfg *config.Config) (*RoleMapper, error) {
+	mapping := cfg.RoleMapping
+
+	rules := mapping.Rules
+	if len(rules) == 0 {
+		// Casdoor roles named like a local role, highest privilege first
+		for _, role := range localRoles {
+			rules = append(rules, config.RoleRule{Role: role, CasdoorRole: role})
+		}
+	}
+
+	for i, rule := range rules {
+		if !slices.Contains(localRoles, rule.Role) {
+			return nil, fmt.Errorf("role mapping rule %d: unknown role %q", i, rule.Role)
+		}
+		if rule.CasdoorRole == "" && rule.Group == "" && rule.EmailDomain == "" && rule.Tag == "" {
+			return nil, fmt.Errorf("role mapping rule %d: casdoor_role, group, email_domain or tag is required", i)
+		}
+	}
+
+	fallback := mapping.Default
+	if fallback == "" {
+		fallback = defaultRole
+	} else if !slices.Contains(localRoles, fallback) {
+		return nil, fmt.Errorf("unknown default role %q", fallback)
+	}
+
+	return &RoleMapper{
+		rules:        rules,
+		defaultRole:  fallback,
+		denyUnmapped: mapping.DenyUnmapped,
+	}, nil
+}
+
+// Map returns the role of the first rule matching claims, the default role or
+// ErrRoleUnmapped when none does
+func (m *RoleMapper) Map(claims *casdoorsdk.Claims) (string, error) {
+	for _, rule := range m.rules {
+		if matchesRule(rule, claims) {
+			return rule.Role, nil
+		}
+	}
+
+	if m.denyUnmapped {
+		return "", ErrRoleUnmapped
+	}
+	return m.defaultRole, nil
+}
+
+// CasdoorRoleFor returns the Casdoor role granting role without further
+// conditions, the one local role changes are mirrored to
+func (m *RoleMapper) CasdoorRoleFor(role string) (string, bool) {
+	for _, rule := range m.rules {
+		if rule.Role == role && rule.CasdoorRole != "" && rule.Group == "" && rule.EmailDomain == "" && rule.Tag == "" {
+			return rule.CasdoorRole, true
+		}
+	}
+	return "", false
+}
+
+// CasdoorRoles returns every Casdoor role a rule matches on
+func (m *RoleMapper) CasdoorRoles() []string {
+	var roles []string
+	for _, rule := range m.rules {
+		if rule.CasdoorRole != "" && !slices.Contains(roles, rule.CasdoorRole) {
+			roles = append(roles, rule.CasdoorRole)
+		}
+	}
+	return roles
+}
+
+// IsLocalRole reports whether role is allowed by the users.role constraint
+func IsLocalRole(role string) bool {
+	return slices.Contains(localRoles, role)
+}
+
+func matchesRule(rule config.RoleRule, claims *casdoorsdk.Claims) bool {
+	if rule.CasdoorRole != "" && !hasCasdoorRole(claims, rule.CasdoorRole) {
+		return false
+	}
+	if rule.Group != "" && !inGroup(claims.Groups, rule.Group) {
+		return false
+	}
+	// Anyone can sign up with any address, only a verified one proves the domain
+	if rule.EmailDomain != "" && (!claims.EmailVerified || !hasEmailDomain(claims.Email, rule.EmailDomain)) {
+		return false
+	}
+	if rule.Tag != "" && claims.Tag != rule.Tag {
+		return false
+	}
+	return true
+}
+
+func hasCasdoorRole(claims *casdoorsdk.Claims, name string) bool {
+	for _, role := range claims.Roles {
+		if role != nil && role.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// inGroup reports whether one of groups is path or one of its subgroups
+func inGroup(groups []string, path string) bool {
+	path = strings.Trim(path, "/")
+	for _, group := range groups {
+		group = strings.Trim(group, "/")
+		if group == path || strings.HasPrefix(group, path+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func hasEmailDomain(email, domain string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	return strings.EqualFold(email[at+1:], strings.TrimPrefix(domain, "@"))
+}
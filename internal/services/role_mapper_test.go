@@ -0,0 +1,146 @@
+package services
+
+import (
+	"errors"
+	"github.com/SAP-2025/auth-service/internal/config"
+	"testing"
+
+	"github.com/casdoor/casdoor-go-sdk/casdoorsdk"
+)
+
+func newTestRoleMapper(t *testing.T, rules []config.RoleRule, fallback string, denyUnmapped bool) *RoleMapper {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.RoleMapping.Rules = rules
+	cfg.RoleMapping.Default = fallback
+	cfg.RoleMapping.DenyUnmapped = denyUnmapped
+
+	mapper, err := NewRoleMapper(cfg)
+	if err != nil {
+		t.Fatalf("NewRoleMapper() error = %v", err)
+	}
+	return mapper
+}
+
+func casdoorClaims(roles []string, groups []string, email string, emailVerified bool, tag string) *casdoorsdk.Claims {
+	claims := &casdoorsdk.Claims{}
+	for _, role := range roles {
+		claims.Roles = append(claims.Roles, &casdoorsdk.Role{Name: role})
+	}
+	claims.Groups = groups
+	claims.Email = email
+	claims.EmailVerified = emailVerified
+	claims.Tag = tag
+	return claims
+}
+
+func TestRoleMapperMap(t *testing.T) {
+	rules := []config.RoleRule{
+		{Role: "admin", CasdoorRole: "platform-admin"},
+		{Role: "proctor", Group: "uni/proctors"},
+		{Role: "teacher", CasdoorRole: "staff", EmailDomain: "uni.edu"},
+		{Role: "teacher", Tag: "faculty"},
+		{Role: "student", EmailDomain: "@students.uni.edu"},
+	}
+	mapper := newTestRoleMapper(t, rules, "", false)
+
+	tests := []struct {
+		name   string
+		claims *casdoorsdk.Claims
+		want   string
+	}{
+		{"first matching rule wins", casdoorClaims([]string{"staff", "platform-admin"}, []string{"uni/proctors"}, "a@uni.edu", true, "faculty"), "admin"},
+		{"group rule", casdoorClaims(nil, []string{"uni/proctors"}, "", false, ""), "proctor"},
+		{"subgroup matches", casdoorClaims(nil, []string{"/uni/proctors/exams/"}, "", false, ""), "proctor"},
+		{"group prefix is not a subgroup", casdoorClaims(nil, []string{"uni/proctors-old"}, "", false, "faculty"), "teacher"},
+		{"every field of a rule must match", casdoorClaims([]string{"staff"}, nil, "a@uni.edu", true, ""), "teacher"},
+		{"role without its email domain", casdoorClaims([]string{"staff"}, nil, "a@gmail.com", true, ""), defaultRole},
+		{"unverified email domain", casdoorClaims([]string{"staff"}, nil, "a@uni.edu", false, ""), defaultRole},
+		{"email domain is case insensitive", casdoorClaims(nil, nil, "A@Students.UNI.edu", true, ""), "student"},
+		{"subdomain is another domain", casdoorClaims([]string{"staff"}, nil, "a@mail.uni.edu", true, ""), defaultRole},
+		{"tag rule", casdoorClaims(nil, nil, "", false, "faculty"), "teacher"},
+		{"unmapped user gets the default role", casdoorClaims([]string{"guest"}, nil, "", false, ""), defaultRole},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mapper.Map(tt.claims)
+			if err != nil {
+				t.Fatalf("Map() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Map() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoleMapperUnmapped(t *testing.T) {
+	rules := []config.RoleRule{{Role: "teacher", CasdoorRole: "staff"}}
+	claims := casdoorClaims([]string{"guest"}, nil, "", false, "")
+
+	tests := []struct {
+		name         string
+		fallback     string
+		denyUnmapped bool
+		want         string
+		wantErr      error
+	}{
+		{"built-in default", "", false, defaultRole, nil},
+		{"configured default", "proctor", false, "proctor", nil},
+		{"deny unmapped", "", true, "", ErrRoleUnmapped},
+		{"deny unmapped ignores the default", "proctor", true, "", ErrRoleUnmapped},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapper := newTestRoleMapper(t, rules, tt.fallback, tt.denyUnmapped)
+			got, err := mapper.Map(claims)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Map() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Map() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// A mapped user is never denied
+	mapper := newTestRoleMapper(t, rules, "", true)
+	if got, err := mapper.Map(casdoorClaims([]string{"staff"}, nil, "", false, "")); err != nil || got != "teacher" {
+		t.Errorf("Map() = %q, %v, want teacher", got, err)
+	}
+}
+
+func TestRoleMapperDefaultRules(t *testing.T) {
+	mapper := newTestRoleMapper(t, nil, "", false)
+
+	// Casdoor roles named like local roles, highest privilege first
+	got, err := mapper.Map(casdoorClaims([]string{"student", "teacher", "admin"}, nil, "", false, ""))
+	if err != nil || got != "admin" {
+		t.Errorf("Map() = %q, %v, want admin", got, err)
+	}
+}
+
+func TestNewRoleMapperRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []config.RoleRule
+		def   string
+	}{
+		{"unknown role", []config.RoleRule{{Role: "superuser", CasdoorRole: "root"}}, ""},
+		{"rule without condition", []config.RoleRule{{Role: "admin"}}, ""},
+		{"unknown default role", nil, "guest"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.RoleMapping.Rules = tt.rules
+			cfg.RoleMapping.Default = tt.def
+			if _, err := NewRoleMapper(cfg); err == nil {
+				t.Error("NewRoleMapper() succeeded, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,344 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/SAP-2025/auth-service/internal/config"
+	"github.com/SAP-2025/auth-service/internal/db"
+	"github.com/SAP-2025/auth-service/internal/models"
+	"github.com/SAP-2025/auth-service/internal/utils"
+	"log"
+	"net"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/oauth2"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenExpired = errors.New("refresh token expired")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrSessionLimitReached = errors.New("maximum number of concurrent sessions reached")
+)
+
+// Reasons reported with logout events
+const (
+	LogoutReasonUser         = "user"
+	LogoutReasonAdmin        = "admin"
+	LogoutReasonExpired      = "expired"
+	LogoutReasonReuse        = "reuse"
+	LogoutReasonSessionLimit = "session_limit"
+	LogoutReasonRevoked      = "revoked"
+	LogoutReasonRoleUnmapped = "role_unmapped"
+	LogoutReasonDeactivated  = "deactivated"
+	LogoutReasonRoleChanged  = "role_changed"
+)
+
+// ClientInfo describes the client a session was created or used from
+type ClientInfo struct {
+	IPAddress string
+	UserAgent string
+}
+
+// RotatedToken records the session a rotated refresh token belonged to.
+// All refresh tokens issued for one session form a token family.
+type RotatedToken struct {
+	SessionID string `json:"session_id"`
+	UserID    uint   `json:"user_id"`
+}
+
+type SessionService struct {
+	sessions db.SessionRepository
+	redis    *redis.Client
+	ttl      time.Duration
+	secret   string
+}
+
+func NewSessionService(sessions db.SessionRepository, redisClient *redis.Client, cfg *config.Config) (*SessionService, error) {
+	secret := cfg.EncryptionKey()
+	if secret == "" {
+		return nil, fmt.Errorf("jwt.signing.encryption_key is required to store Casdoor tokens")
+	}
+
+	return &SessionService{
+		sessions: sessions,
+		redis:    redisClient,
+		ttl:      cfg.RefreshTokenTTL(),
+		secret:   secret,
+	}, nil
+}
+
+// CreateSession stores a new session and returns it with its raw refresh token
+func (s *SessionService) CreateSession(ctx context.Context, sessionID uuid.UUID, userID uint, accessTokenID string, upstream *oauth2.Token, client ClientInfo) (*models.UserSession, string, error) {
+	refreshToken, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	casdoorIDToken, err := s.sealCasdoorToken(casdoorIDToken(upstream))
+	if err != nil {
+		return nil, "", err
+	}
+	casdoorRefreshToken, err := s.sealCasdoorToken(upstream.RefreshToken)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	session := &models.UserSession{
+		ID:                  sessionID,
+		UserID:              userID,
+		RefreshTokenHash:    utils.HashToken(refreshToken),
+		ExpiresAt:           now.Add(s.ttl),
+		CreatedAt:           now,
+		LastUsedAt:          now,
+		UserAgent:           client.UserAgent,
+		IPAddress:           net.ParseIP(client.IPAddress),
+		AccessTokenID:       accessTokenID,
+		CasdoorIDToken:      casdoorIDToken,
+		CasdoorRefreshToken: casdoorRefreshToken,
+	}
+
+	if err := s.sessions.Create(ctx, session); err != nil {
+		return nil, "", fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return session, refreshToken, nil
+}
+
+// GetByRefreshToken looks up the session owning refreshToken
+func (s *SessionService) GetByRefreshToken(ctx context.Context, refreshToken string) (*models.UserSession, error) {
+	session, err := s.sessions.GetByRefreshTokenHash(ctx, utils.HashToken(refreshToken))
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, ErrInvalidRefreshToken
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	if time.Now().After(session.ExpiresAt) {
+		s.sessions.DeleteByID(ctx, session.ID)
+		return nil, ErrRefreshTokenExpired
+	}
+
+	return session, nil
+}
+
+// RotateRefreshToken replaces the refresh token of session and returns the new raw token.
+// The update only applies while the session still holds oldRefreshToken, so two
+// concurrent refreshes with the same token cannot both succeed.
+func (s *SessionService) RotateRefreshToken(ctx context.Context, session *models.UserSession, oldRefreshToken, accessTokenID string, upstream *oauth2.Token, client ClientInfo) (string, error) {
+	refreshToken, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	// Casdoor may keep its refresh token and skip the ID token when renewing
+	casdoorRefreshToken := session.CasdoorRefreshToken
+	if upstream.RefreshToken != "" {
+		casdoorRefreshToken, err = s.sealCasdoorToken(upstream.RefreshToken)
+		if err != nil {
+			return "", err
+		}
+	}
+	sealedIDToken := session.CasdoorIDToken
+	if idToken := casdoorIDToken(upstream); idToken != "" {
+		sealedIDToken, err = s.sealCasdoorToken(idToken)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"refresh_token_hash":    utils.HashToken(refreshToken),
+		"last_used_at":          now,
+		"user_agent":            client.UserAgent,
+		"ip_address":            net.ParseIP(client.IPAddress),
+		"access_token_id":       accessTokenID,
+		"casdoor_id_token":      sealedIDToken,
+		"casdoor_refresh_token": casdoorRefreshToken,
+	}
+
+	rows, err := s.sessions.UpdateIfHash(ctx, session.ID, utils.HashToken(oldRefreshToken), updates)
+	if err != nil {
+		return "", fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	if rows == 0 {
+		return "", ErrInvalidRefreshToken
+	}
+
+	// Remember the consumed token until the session expires so a replay can be detected
+	rotated, _ := json.Marshal(RotatedToken{SessionID: session.ID.String(), UserID: session.UserID})
+	if err := s.redis.Set(ctx, s.getRotatedKey(oldRefreshToken), rotated, time.Until(session.ExpiresAt)).Err(); err != nil {
+		return "", fmt.Errorf("failed to record rotated refresh token: %w", err)
+	}
+
+	session.RefreshTokenHash = utils.HashToken(refreshToken)
+	session.LastUsedAt = now
+	session.AccessTokenID = accessTokenID
+	session.CasdoorIDToken = sealedIDToken
+	session.CasdoorRefreshToken = casdoorRefreshToken
+
+	return refreshToken, nil
+}
+
+// OpenCasdoorToken decrypts a Casdoor token stored with a session
+func (s *SessionService) OpenCasdoorToken(sealed string) (string, error) {
+	if sealed == "" {
+		return "", nil
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode Casdoor token: %w", err)
+	}
+	token, err := utils.Decrypt(s.secret, ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt Casdoor token: %w", err)
+	}
+	return string(token), nil
+}
+
+// sealCasdoorToken encrypts a Casdoor token, a leaked session row must not
+// hand out usable upstream credentials
+func (s *SessionService) sealCasdoorToken(token string) (string, error) {
+	if token == "" {
+		return "", nil
+	}
+
+	ciphertext, err := utils.Encrypt(s.secret, []byte(token))
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt Casdoor token: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// casdoorIDToken returns the raw ID token of a Casdoor token response
+func casdoorIDToken(upstream *oauth2.Token) string {
+	idToken, _ := upstream.Extra("id_token").(string)
+	return idToken
+}
+
+// releaseLockScript deletes a lock only while it still holds the caller's
+// token, so a holder that outlived the TTL cannot release the next holder's lock
+var releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// LockUser serializes the logins of userID across instances, so concurrent
+// logins cannot all pass the session limit. The returned function releases the lock.
+func (s *SessionService) LockUser(ctx context.Context, userID uint) (func(), error) {
+	lockKey := fmt.Sprintf("session:lock:%d", userID)
+	token, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock sessions: %w", err)
+	}
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(10 * time.Second)
+
+	for {
+		acquired, err := s.redis.SetNX(ctx, lockKey, token, 10*time.Second).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to lock sessions: %w", err)
+		}
+		if acquired {
+			// Released even when the request was cancelled meanwhile
+			releaseCtx := context.WithoutCancel(ctx)
+			return func() {
+				if err := releaseLockScript.Run(releaseCtx, s.redis, []string{lockKey}, token).Err(); err != nil {
+					log.Printf("Failed to release session lock of user %d: %v", userID, err)
+				}
+			}, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timeout:
+			return nil, errors.New("timed out waiting for the session lock")
+		case <-ticker.C:
+		}
+	}
+}
+
+// FindRotatedToken reports whether refreshToken was already rotated out of a session
+func (s *SessionService) FindRotatedToken(ctx context.Context, refreshToken string) (*RotatedToken, bool) {
+	data, err := s.redis.Get(ctx, s.getRotatedKey(refreshToken)).Result()
+	if err != nil {
+		return nil, false
+	}
+
+	var rotated RotatedToken
+	if err := json.Unmarshal([]byte(data), &rotated); err != nil {
+		return nil, false
+	}
+
+	return &rotated, true
+}
+
+// DeleteSession removes a single session of userID
+func (s *SessionService) DeleteSession(ctx context.Context, userID uint, sessionID string) (int64, error) {
+	rows, err := s.sessions.Delete(ctx, userID, sessionID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete session: %w", err)
+	}
+	return rows, nil
+}
+
+// ListSessions returns the active sessions of userID, most recently used first
+func (s *SessionService) ListSessions(ctx context.Context, userID uint) ([]models.UserSession, error) {
+	sessions, err := s.sessions.ListActive(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// GetSession returns a single session of userID
+func (s *SessionService) GetSession(ctx context.Context, userID uint, sessionID string) (*models.UserSession, error) {
+	session, err := s.sessions.Get(ctx, userID, sessionID)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, ErrSessionNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	return session, nil
+}
+
+// DeleteExpiredBatch removes up to limit expired sessions and returns them
+func (s *SessionService) DeleteExpiredBatch(ctx context.Context, limit int) ([]models.UserSession, error) {
+	expired, err := s.sessions.FindExpired(ctx, time.Now(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find expired sessions: %w", err)
+	}
+	if len(expired) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(expired))
+	for _, session := range expired {
+		ids = append(ids, session.ID)
+	}
+
+	if err := s.sessions.DeleteByIDs(ctx, ids); err != nil {
+		return nil, fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+
+	return expired, nil
+}
+
+// Helper function to generate Redis key
+func (s *SessionService) getRotatedKey(refreshToken string) string {
+	return fmt.Sprintf("refresh:rotated:%s", utils.HashToken(refreshToken))
+}
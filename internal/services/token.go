@@ -0,0 +1,130 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"github.com/SAP-2025/auth-service/internal/config"
+	"github.com/SAP-2025/auth-service/internal/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+var ErrInvalidAccessToken = errors.New("invalid access token")
+
+// AccessClaims are the claims of access tokens minted by this service.
+// Subject is the local models.User ID.
+type AccessClaims struct {
+	Username     string `json:"preferred_username"`
+	Email        string `json:"email,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Role         string `json:"role"`
+	Organization string `json:"org,omitempty"`
+	SessionID    string `json:"sid,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// UserID returns the local user ID carried in the subject claim
+func (c *AccessClaims) UserID() (uint, error) {
+	id, err := strconv.ParseUint(c.Subject, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid subject %q: %w", c.Subject, err)
+	}
+	return uint(id), nil
+}
+
+type TokenService struct {
+	keys     *KeyManager
+	issuer   string
+	audience []string
+	scope    string
+	ttl      time.Duration
+}
+
+func NewTokenService(keys *KeyManager, cfg *config.Config) *TokenService {
+	return &TokenService{
+		keys:     keys,
+		issuer:   cfg.JWT.Issuer,
+		audience: cfg.TokenAudience(),
+		scope:    strings.Join(cfg.TokenScopes(), " "),
+		ttl:      cfg.AccessTokenTTL(),
+	}
+}
+
+// TTL returns the lifetime of issued access tokens
+func (t *TokenService) TTL() time.Duration {
+	return t.ttl
+}
+
+// IssueAccessToken mints a signed access token for user bound to sessionID
+func (t *TokenService) IssueAccessToken(user *models.User, sessionID string) (string, *AccessClaims, error) {
+	now := time.Now()
+	claims := &AccessClaims{
+		Username:     user.Username,
+		Email:        user.Email,
+		Name:         user.Name,
+		Role:         user.Role,
+		Organization: user.Organization,
+		SessionID:    sessionID,
+		Scope:        t.scope,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    t.issuer,
+			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+			Audience:  t.audience,
+			ExpiresAt: jwt.NewNumericDate(now.Add(t.ttl)),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        uuid.New().String(),
+		},
+	}
+
+	kid, method, key, err := t.keys.SigningKey()
+	if err != nil {
+		return "", nil, err
+	}
+
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+
+	signed, err := token.SignedString(key)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to sign access token: %w", err)
+	}
+
+	return signed, claims, nil
+}
+
+// ParseAccessToken verifies the signature, issuer, audience and expiry of an access token
+func (t *TokenService) ParseAccessToken(accessToken string) (*AccessClaims, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(t.keys.Algorithms()),
+		jwt.WithIssuer(t.issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithAudience(t.audience...),
+	}
+
+	claims := &AccessClaims{}
+	_, err := jwt.ParseWithClaims(accessToken, claims, t.verificationKey, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAccessToken, err)
+	}
+
+	return claims, nil
+}
+
+// verificationKey resolves the public key of the kid a token was signed with
+func (t *TokenService) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, algorithm, ok := t.keys.VerificationKey(kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != algorithm {
+		return nil, fmt.Errorf("signing key %q does not use %s", kid, token.Method.Alg())
+	}
+	return key, nil
+}
@@ -0,0 +1,289 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/SAP-2025/auth-service/internal/db"
+	"github.com/SAP-2025/auth-service/internal/models"
+	"log"
+	"time"
+
+	"github.com/casdoor/casdoor-go-sdk/casdoorsdk"
+	"github.com/redis/go-redis/v9"
+)
+
+// How long the active flag of a user is served from Redis
+const activeFlagTTL = 5 * time.Minute
+
+var (
+	ErrUserNotFound = errors.New("user not found")
+	ErrUserConflict = errors.New("user conflicts with an existing account")
+	ErrUserInactive = errors.New("user account is deactivated")
+)
+
+// UserInfo is the public representation of a local user
+type UserInfo struct {
+	ID           uint   `json:"id"`
+	Username     string `json:"username"`
+	Email        string `json:"email"`
+	Name         string `json:"name"`
+	AvatarURL    string `json:"avatar_url,omitempty"`
+	Role         string `json:"role"`
+	Organization string `json:"organization,omitempty"`
+}
+
+func NewUserInfo(user *models.User) *UserInfo {
+	return &UserInfo{
+		ID:           user.ID,
+		Username:     user.Username,
+		Email:        user.Email,
+		Name:         user.Name,
+		AvatarURL:    user.AvatarURL,
+		Role:         user.Role,
+		Organization: user.Organization,
+	}
+}
+
+type UserService struct {
+	repos *db.Repositories
+	roles *RoleMapper
+	redis *redis.Client
+}
+
+func NewUserService(repos *db.Repositories, roles *RoleMapper, redisClient *redis.Client) *UserService {
+	return &UserService{repos: repos, roles: roles, redis: redisClient}
+}
+
+// UpsertUser creates or updates the local user of the Casdoor claims and
+// records the login. created reports whether this was the first login.
+func (s *UserService) UpsertUser(ctx context.Context, claims *casdoorsdk.Claims) (user *models.User, created bool, err error) {
+	// A concurrent first login of the same user makes the insert fail on the
+	// unique casdoor_user_id, the retry then finds and updates the row
+	for attempt := 0; attempt < 2; attempt++ {
+		err = s.repos.Transaction(ctx, func(tx *db.Repositories) error {
+			existing, err := tx.Users.GetByCasdoorUserIDForUpdate(ctx, claims.Id)
+			if errors.Is(err, db.ErrNotFound) {
+				user, err = s.createUser(ctx, tx.Users, claims)
+				created = err == nil
+				return err
+			} else if err != nil {
+				return err
+			}
+
+			user, err = s.updateUser(ctx, tx.Users, existing, claims)
+			return err
+		})
+		if err == nil || errors.Is(err, ErrUserConflict) || errors.Is(err, ErrRoleUnmapped) {
+			break
+		}
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to provision user: %w", err)
+	}
+
+	return user, created, nil
+}
+
+func (s *UserService) createUser(ctx context.Context, users db.UserRepository, claims *casdoorsdk.Claims) (*models.User, error) {
+	role, err := s.roles.Map(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	email := claims.Email
+	if email == "" {
+		// email is unique and required, .invalid never resolves (RFC 2606)
+		email = claims.Id + "@users.invalid"
+	}
+
+	// Linking to an existing account by email would let anyone controlling the
+	// address in Casdoor take it over, so the login is refused instead
+	taken, err := users.IsTaken(ctx, "email", email, 0)
+	if err != nil {
+		return nil, err
+	} else if taken {
+		return nil, fmt.Errorf("%w: email %s", ErrUserConflict, email)
+	}
+
+	username, err := availableUsername(ctx, users, claims.Name, claims.Id, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		CasdoorUserID: claims.Id,
+		Username:      username,
+		Email:         email,
+		Name:          displayName(claims),
+		AvatarURL:     claims.Avatar,
+		Role:          role,
+		Organization:  claims.Owner,
+		IsActive:      true,
+		LastLoginAt:   time.Now(),
+	}
+	if err := users.Create(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// updateUser syncs the profile from Casdoor. Username and email only follow
+// Casdoor when no other local user holds them.
+func (s *UserService) updateUser(ctx context.Context, users db.UserRepository, user *models.User, claims *casdoorsdk.Claims) (*models.User, error) {
+	role, err := s.roles.Map(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{
+		"name":          displayName(claims),
+		"avatar_url":    claims.Avatar,
+		"organization":  claims.Owner,
+		"role":          role,
+		"last_login_at": time.Now(),
+	}
+
+	if claims.Name != "" && claims.Name != user.Username {
+		username, err := availableUsername(ctx, users, claims.Name, claims.Id, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		updates["username"] = username
+	}
+
+	if claims.Email != "" && claims.Email != user.Email {
+		taken, err := users.IsTaken(ctx, "email", claims.Email, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		if taken {
+			log.Printf("Keeping email of user %d, %s belongs to another user", user.ID, claims.Email)
+		} else {
+			updates["email"] = claims.Email
+		}
+	}
+
+	if err := users.Update(ctx, user.ID, updates); err != nil {
+		return nil, err
+	}
+
+	// Reload so the caller sees the synced profile
+	return users.GetByID(ctx, user.ID)
+}
+
+// availableUsername returns name, or name suffixed with the Casdoor ID when
+// another local user already holds it
+func availableUsername(ctx context.Context, users db.UserRepository, name, casdoorUserID string, userID uint) (string, error) {
+	if name == "" {
+		name = casdoorUserID
+	}
+
+	taken, err := users.IsTaken(ctx, "username", name, userID)
+	if err != nil || !taken {
+		return name, err
+	}
+
+	suffix := casdoorUserID
+	if len(suffix) > 8 {
+		suffix = suffix[:8]
+	}
+	log.Printf("Username %s is taken, using %s-%s", name, name, suffix)
+	return name + "-" + suffix, nil
+}
+
+func displayName(claims *casdoorsdk.Claims) string {
+	if claims.DisplayName != "" {
+		return claims.DisplayName
+	}
+	return claims.Name
+}
+
+// SyncRole re-evaluates the role mapping for user, so role changes in Casdoor
+// apply without a new login
+func (s *UserService) SyncRole(ctx context.Context, user *models.User, claims *casdoorsdk.Claims) (*models.User, error) {
+	role, err := s.roles.Map(claims)
+	if err != nil {
+		return nil, err
+	}
+	if role == user.Role {
+		return user, nil
+	}
+
+	if err := s.repos.Users.Update(ctx, user.ID, map[string]interface{}{"role": role}); err != nil {
+		return nil, fmt.Errorf("failed to update role: %w", err)
+	}
+	log.Printf("Role of user %d changed from %s to %s", user.ID, user.Role, role)
+
+	user.Role = role
+	return user, nil
+}
+
+// IsActive reports whether a user may sign in and use their tokens. The flag
+// is served from Redis, SetActive replaces the cached copy at once.
+func (s *UserService) IsActive(ctx context.Context, userID uint) (bool, error) {
+	cached, err := s.redis.Get(ctx, getActiveFlagKey(userID)).Result()
+	if err == nil {
+		return cached == "1", nil
+	} else if !errors.Is(err, redis.Nil) {
+		log.Printf("Failed to read active flag of user %d: %v", userID, err)
+	}
+
+	user, err := s.GetByID(ctx, userID)
+	if errors.Is(err, ErrUserNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	s.cacheActive(ctx, userID, user.IsActive)
+	return user.IsActive, nil
+}
+
+// SetActive stores the active flag of a user
+func (s *UserService) SetActive(ctx context.Context, userID uint, active bool) error {
+	if err := s.repos.Users.Update(ctx, userID, map[string]interface{}{"is_active": active}); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	s.cacheActive(ctx, userID, active)
+	return nil
+}
+
+func (s *UserService) cacheActive(ctx context.Context, userID uint, active bool) {
+	value := "0"
+	if active {
+		value = "1"
+	}
+	if err := s.redis.Set(ctx, getActiveFlagKey(userID), value, activeFlagTTL).Err(); err != nil {
+		log.Printf("Failed to cache active flag of user %d: %v", userID, err)
+	}
+}
+
+func getActiveFlagKey(userID uint) string {
+	return fmt.Sprintf("user:active:%d", userID)
+}
+
+// GetByID returns a local user
+func (s *UserService) GetByID(ctx context.Context, id uint) (*models.User, error) {
+	return lookupUser(s.repos.Users.GetByID(ctx, id))
+}
+
+// GetByUsername returns the local user with the given username
+func (s *UserService) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	return lookupUser(s.repos.Users.GetByUsername(ctx, username))
+}
+
+// GetByCasdoorUserID returns the local user linked to a Casdoor account
+func (s *UserService) GetByCasdoorUserID(ctx context.Context, casdoorUserID string) (*models.User, error) {
+	return lookupUser(s.repos.Users.GetByCasdoorUserID(ctx, casdoorUserID))
+}
+
+func lookupUser(user *models.User, err error) (*models.User, error) {
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, ErrUserNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return user, nil
+}
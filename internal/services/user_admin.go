@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/SAP-2025/auth-service/internal/config"
+	"github.com/SAP-2025/auth-service/internal/db"
+	"github.com/SAP-2025/auth-service/internal/models"
+	"log"
+	"slices"
+
+	"github.com/casdoor/casdoor-go-sdk/casdoorsdk"
+)
+
+// Number of audit entries returned with a user
+const recentAuthLogs = 50
+
+var (
+	ErrInvalidRole      = errors.New("unknown role")
+	ErrRoleNotMirrored  = errors.New("no casdoor role is mapped to the role")
+	ErrRoleOverridden   = errors.New("a higher priority role mapping rule overrides the role")
+	ErrSelfModification = errors.New("admins cannot change their own role or status")
+	ErrCasdoorSync      = errors.New("failed to update the user in casdoor")
+)
+
+// UserDetail is a user with their active sessions and latest audit entries
+type UserDetail struct {
+	User     *models.User
+	Sessions []models.UserSession
+	AuthLogs []models.AuthLog
+}
+
+// UserAdminService lets admins manage local users. Changes are made in
+// Casdoor first, so the next login or refresh does not undo them.
+type UserAdminService struct {
+	repos          *db.Repositories
+	roles          *RoleMapper
+	userService    *UserService
+	authService    *AuthService
+	eventService   *EventService
+	authLogService *AuthLogService
+	casdoorClient  *casdoorsdk.Client
+}
+
+func NewUserAdminService(repos *db.Repositories, roles *RoleMapper, userService *UserService, authService *AuthService, eventService *EventService, authLogService *AuthLogService, cfg *config.Config) *UserAdminService {
+	return &UserAdminService{
+		repos:          repos,
+		roles:          roles,
+		userService:    userService,
+		authService:    authService,
+		eventService:   eventService,
+		authLogService: authLogService,
+		casdoorClient:  config.NewCasdoorClient(cfg),
+	}
+}
+
+// ListUsers returns a page of users and the number of users matching filter
+func (s *UserAdminService) ListUsers(ctx context.Context, filter db.UserFilter) ([]models.User, int64, error) {
+	users, total, err := s.repos.Users.List(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list users: %w", err)
+	}
+	return users, total, nil
+}
+
+// GetUserDetail returns a user with their sessions and recent audit entries
+func (s *UserAdminService) GetUserDetail(ctx context.Context, userID uint) (*UserDetail, error) {
+	user, err := lookupUser(s.repos.Users.GetByID(ctx, userID))
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := s.repos.Sessions.ListActive(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	authLogs, err := s.repos.AuthLogs.ListByUser(ctx, userID, recentAuthLogs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list auth logs: %w", err)
+	}
+
+	return &UserDetail{User: user, Sessions: sessions, AuthLogs: authLogs}, nil
+}
+
+// SetRole moves the user to the Casdoor role mapped to role and stores it
+// locally. The sessions of the user are revoked so no token keeps the old role.
+func (s *UserAdminService) SetRole(ctx context.Context, actor *models.User, userID uint, role string, client ClientInfo) (*models.User, error) {
+	if !IsLocalRole(role) {
+		return nil, ErrInvalidRole
+	}
+	casdoorRole, ok := s.roles.CasdoorRoleFor(role)
+	if !ok {
+		return nil, fmt.Errorf("%w %s", ErrRoleNotMirrored, role)
+	}
+
+	user, err := s.targetUser(ctx, actor, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role == role {
+		return user, nil
+	}
+
+	casdoorUser, err := s.casdoorUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	// Roles are mapped again on every refresh, a group, email domain or tag rule
+	// ranking above the Casdoor role would silently undo the change
+	claims := &casdoorsdk.Claims{User: *casdoorUser}
+	claims.Roles = []*casdoorsdk.Role{{Name: casdoorRole}}
+	mapped, err := s.roles.Map(claims)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRoleOverridden, err)
+	}
+	if mapped != role {
+		return nil, fmt.Errorf("%w: the user would be mapped to %s", ErrRoleOverridden, mapped)
+	}
+
+	if err := s.syncCasdoorRoles(ctx, actor, casdoorUser, casdoorRole, client); err != nil {
+		return nil, err
+	}
+
+	if err := s.repos.Users.Update(ctx, user.ID, map[string]interface{}{"role": role}); err != nil {
+		return nil, fmt.Errorf("failed to update role: %w", err)
+	}
+
+	revoked, err := s.authService.RevokeOtherSessions(ctx, user.ID, "", LogoutReasonRoleChanged, client)
+	if err != nil {
+		log.Printf("Failed to revoke sessions of user %d: %v", user.ID, err)
+	}
+
+	details := fmt.Sprintf("user %d: role %s -> %s, %d sessions revoked", user.ID, user.Role, role, revoked)
+	s.authLogService.RecordAdminAction(ctx, actor.ID, "user_role_changed", client, details)
+	log.Printf("Admin %d changed %s", actor.ID, details)
+
+	user.Role = role
+	return user, nil
+}
+
+// SetActive forbids or allows the user in Casdoor and stores the flag locally.
+// Deactivation also ends every session of the user right away.
+func (s *UserAdminService) SetActive(ctx context.Context, actor *models.User, userID uint, active bool, client ClientInfo) (*models.User, error) {
+	user, err := s.targetUser(ctx, actor, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsActive == active {
+		return user, nil
+	}
+
+	casdoorUser, err := s.casdoorUser(user)
+	if err != nil {
+		return nil, err
+	}
+	casdoorUser.IsForbidden = !active
+	if err := casdoorResult(s.casdoorClient.UpdateUserForColumns(casdoorUser, []string{"isForbidden"})); err != nil {
+		return nil, err
+	}
+
+	if err := s.userService.SetActive(ctx, user.ID, active); err != nil {
+		return nil, err
+	}
+	user.IsActive = active
+
+	if active {
+		s.authLogService.RecordAdminAction(ctx, actor.ID, "user_activated", client, fmt.Sprintf("user %d", user.ID))
+		log.Printf("Admin %d activated user %d", actor.ID, user.ID)
+		return user, nil
+	}
+
+	// The cached flag already rejects the tokens, revoking them covers
+	// services validating tokens offline
+	revoked, err := s.authService.RevokeOtherSessions(ctx, user.ID, "", LogoutReasonDeactivated, client)
+	if err != nil {
+		log.Printf("Failed to revoke sessions of deactivated user %d: %v", user.ID, err)
+	}
+
+	details := fmt.Sprintf("user %d, %d sessions revoked", user.ID, revoked)
+	s.authLogService.RecordAdminAction(ctx, actor.ID, "user_deactivated", client, details)
+	log.Printf("Admin %d deactivated %s", actor.ID, details)
+
+	if err := s.eventService.PublishUserDeactivatedEvent(user.ID, actor.ID, revoked); err != nil {
+		log.Printf("Failed to publish user deactivated event: %v", err)
+	}
+	return user, nil
+}
+
+// targetUser loads the user an admin changes; admins cannot lock themselves out
+func (s *UserAdminService) targetUser(ctx context.Context, actor *models.User, userID uint) (*models.User, error) {
+	if actor.ID == userID {
+		return nil, ErrSelfModification
+	}
+	return lookupUser(s.repos.Users.GetByID(ctx, userID))
+}
+
+func (s *UserAdminService) casdoorUser(user *models.User) (*casdoorsdk.User, error) {
+	casdoorUser, err := s.casdoorClient.GetUserByUserId(user.CasdoorUserID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCasdoorSync, err)
+	}
+	if casdoorUser == nil {
+		return nil, fmt.Errorf("%w: user %s not found", ErrCasdoorSync, user.CasdoorUserID)
+	}
+	return casdoorUser, nil
+}
+
+// syncCasdoorRoles makes the user a member of target and removes them from
+// every other mapped Casdoor role, which could outrank target. Casdoor has no
+// transactions: target is joined first, so a failure later leaves the user with
+// an extra role rather than none, and is recorded in the audit log.
+func (s *UserAdminService) syncCasdoorRoles(ctx context.Context, actor *models.User, casdoorUser *casdoorsdk.User, target string, client ClientInfo) error {
+	member := casdoorUser.Owner + "/" + casdoorUser.Name
+
+	if _, err := s.changeCasdoorRole(target, member, true); err != nil {
+		return err
+	}
+
+	var removed []string
+	for _, name := range s.roles.CasdoorRoles() {
+		if name == target {
+			continue
+		}
+		changed, err := s.changeCasdoorRole(name, member, false)
+		if err != nil {
+			details := fmt.Sprintf("casdoor user %s joined %s, removed from %v, failed on %s: %v", member, target, removed, name, err)
+			s.authLogService.RecordAdminAction(ctx, actor.ID, "user_role_change_incomplete", client, details)
+			log.Printf("Admin %d role change incomplete: %s", actor.ID, details)
+			return err
+		}
+		if changed {
+			removed = append(removed, name)
+		}
+	}
+	return nil
+}
+
+// changeCasdoorRole adds member to or removes it from the Casdoor role name
+// and reports whether the role changed. The role is read right before it is
+// written to keep the window for concurrent edits short.
+func (s *UserAdminService) changeCasdoorRole(name, member string, join bool) (bool, error) {
+	role, err := s.casdoorClient.GetRole(name)
+	if err != nil {
+		return false, fmt.Errorf("%w: role %s: %v", ErrCasdoorSync, name, err)
+	}
+	if role == nil {
+		if join {
+			return false, fmt.Errorf("%w: role %s not found", ErrCasdoorSync, name)
+		}
+		return false, nil
+	}
+
+	if slices.Contains(role.Users, member) == join {
+		return false, nil
+	}
+	if join {
+		role.Users = append(role.Users, member)
+	} else {
+		role.Users = slices.DeleteFunc(role.Users, func(u string) bool { return u == member })
+	}
+
+	if err := casdoorResult(s.casdoorClient.UpdateRole(role)); err != nil {
+		return false, fmt.Errorf("role %s: %w", name, err)
+	}
+	return true, nil
+}
+
+// casdoorResult turns the outcome of a Casdoor update into an error
+func casdoorResult(ok bool, err error) error {
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCasdoorSync, err)
+	}
+	if !ok {
+		return fmt.Errorf("%w: update rejected", ErrCasdoorSync)
+	}
+	return nil
+}
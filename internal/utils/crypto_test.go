@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	plaintext := []byte("casdoor-refresh-token")
+
+	ciphertext, err := Encrypt("secret", plaintext)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if bytes.Contains(ciphertext, plaintext) {
+		t.Error("Encrypt() output contains the plaintext")
+	}
+
+	got, err := Decrypt("secret", ciphertext)
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("Decrypt() = %q, want %q", got, plaintext)
+	}
+
+	// A fresh nonce per call, equal plaintexts must not be linkable
+	again, err := Encrypt("secret", plaintext)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if bytes.Equal(again, ciphertext) {
+		t.Error("Encrypt() returned the same ciphertext twice")
+	}
+}
+
+func TestDecryptRejects(t *testing.T) {
+	ciphertext, err := Encrypt("secret", []byte("casdoor-refresh-token"))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	tampered := bytes.Clone(ciphertext)
+	tampered[len(tampered)-1] ^= 0x01
+	tamperedNonce := bytes.Clone(ciphertext)
+	tamperedNonce[0] ^= 0x01
+
+	tests := []struct {
+		name       string
+		secret     string
+		ciphertext []byte
+	}{
+		{"tampered ciphertext", "secret", tampered},
+		{"tampered nonce", "secret", tamperedNonce},
+		{"wrong key", "other-secret", ciphertext},
+		{"truncated tag", "secret", ciphertext[:len(ciphertext)-1]},
+		{"shorter than the nonce", "secret", ciphertext[:5]},
+		{"empty", "secret", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := Decrypt(tt.secret, tt.ciphertext); err == nil {
+				t.Errorf("Decrypt() = %q, want an error", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package utils
+
+import "strings"
+
+type UserAgentInfo struct {
+	Browser string `json:"browser"`
+	OS      string `json:"os"`
+	Device  string `json:"device"`
+}
+
+// ParseUserAgent extracts a coarse browser, OS and device type from a User-Agent header.
+// Order matters: most browsers also advertise the engines they are compatible with.
+func ParseUserAgent(ua string) UserAgentInfo {
+	info := UserAgentInfo{Browser: "Unknown", OS: "Unknown", Device: "Desktop"}
+
+	switch {
+	case strings.Contains(ua, "Edg/"):
+		info.Browser = "Edge"
+	case strings.Contains(ua, "OPR/"):
+		info.Browser = "Opera"
+	case strings.Contains(ua, "Firefox/"):
+		info.Browser = "Firefox"
+	case strings.Contains(ua, "Chrome/"), strings.Contains(ua, "CriOS/"):
+		info.Browser = "Chrome"
+	case strings.Contains(ua, "Safari/"):
+		info.Browser = "Safari"
+	}
+
+	switch {
+	case strings.Contains(ua, "Windows"):
+		info.OS = "Windows"
+	case strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPad"):
+		info.OS = "iOS"
+	case strings.Contains(ua, "Mac OS X"):
+		info.OS = "macOS"
+	case strings.Contains(ua, "Android"):
+		info.OS = "Android"
+	case strings.Contains(ua, "CrOS"):
+		info.OS = "ChromeOS"
+	case strings.Contains(ua, "Linux"):
+		info.OS = "Linux"
+	}
+
+	switch {
+	case strings.Contains(ua, "iPad"), info.OS == "Android" && !strings.Contains(ua, "Mobile"):
+		info.Device = "Tablet"
+	case strings.Contains(ua, "Mobi"), strings.Contains(ua, "iPhone"):
+		info.Device = "Mobile"
+	}
+
+	return info
+}
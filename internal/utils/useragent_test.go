@@ -0,0 +1,80 @@
+package utils
+
+import "testing"
+
+func TestParseUserAgent(t *testing.T) {
+	tests := []struct {
+		name string
+		ua   string
+		want UserAgentInfo
+	}{
+		{
+			name: "chrome on windows",
+			ua:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			want: UserAgentInfo{Browser: "Chrome", OS: "Windows", Device: "Desktop"},
+		},
+		{
+			name: "edge advertises chrome and safari",
+			ua:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.0.0",
+			want: UserAgentInfo{Browser: "Edge", OS: "Windows", Device: "Desktop"},
+		},
+		{
+			name: "opera advertises chrome",
+			ua:   "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 OPR/106.0.0.0",
+			want: UserAgentInfo{Browser: "Opera", OS: "Linux", Device: "Desktop"},
+		},
+		{
+			name: "firefox on macos",
+			ua:   "Mozilla/5.0 (Macintosh; Intel Mac OS X 14.2; rv:121.0) Gecko/20100101 Firefox/121.0",
+			want: UserAgentInfo{Browser: "Firefox", OS: "macOS", Device: "Desktop"},
+		},
+		{
+			name: "safari on macos",
+			ua:   "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_2) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Safari/605.1.15",
+			want: UserAgentInfo{Browser: "Safari", OS: "macOS", Device: "Desktop"},
+		},
+		{
+			name: "safari on iphone mentions mac os x",
+			ua:   "Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Mobile/15E148 Safari/604.1",
+			want: UserAgentInfo{Browser: "Safari", OS: "iOS", Device: "Mobile"},
+		},
+		{
+			name: "chrome on ipad",
+			ua:   "Mozilla/5.0 (iPad; CPU OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/120.0.6099.119 Mobile/15E148 Safari/604.1",
+			want: UserAgentInfo{Browser: "Chrome", OS: "iOS", Device: "Tablet"},
+		},
+		{
+			name: "chrome on android phone mentions linux",
+			ua:   "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.144 Mobile Safari/537.36",
+			want: UserAgentInfo{Browser: "Chrome", OS: "Android", Device: "Mobile"},
+		},
+		{
+			name: "android tablet",
+			ua:   "Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.144 Safari/537.36",
+			want: UserAgentInfo{Browser: "Chrome", OS: "Android", Device: "Tablet"},
+		},
+		{
+			name: "chromebook",
+			ua:   "Mozilla/5.0 (X11; CrOS x86_64 14541.0.0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			want: UserAgentInfo{Browser: "Chrome", OS: "ChromeOS", Device: "Desktop"},
+		},
+		{
+			name: "unknown client",
+			ua:   "curl/8.4.0",
+			want: UserAgentInfo{Browser: "Unknown", OS: "Unknown", Device: "Desktop"},
+		},
+		{
+			name: "empty header",
+			ua:   "",
+			want: UserAgentInfo{Browser: "Unknown", OS: "Unknown", Device: "Desktop"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseUserAgent(tt.ua); got != tt.want {
+				t.Errorf("ParseUserAgent() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}